@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/boostgo/errorx"
+)
+
+// BreakerState is state of circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed means calls are allowed and failures are counted
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means calls fail fast with ErrCircuitOpen until open timeout is passed
+	BreakerOpen
+	// BreakerHalfOpen means limited count of trial calls is allowed to check if connection recovered
+	BreakerHalfOpen
+)
+
+// String returns name of breaker state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerSettings describes circuit breaker thresholds. Zero values are replaced by defaults
+type BreakerSettings struct {
+	// FailureThreshold is count of consecutive failures which opens breaker. Default is 5
+	FailureThreshold int
+	// OpenTimeout is duration of open state before trial calls are allowed. Default is 30 seconds
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls is count of concurrent trial calls in half-open state. Default is 1
+	HalfOpenMaxCalls int
+	// SuccessThreshold is count of successful trial calls which closes breaker. Default is 1
+	SuccessThreshold int
+	// IsFailure decides if call error is failure of connection.
+	// By default, every error except context cancellation is failure
+	IsFailure func(err error) bool
+	// OnStateChange is called after breaker changed state. It must not call methods of the breaker
+	OnStateChange func(name string, from, to BreakerState)
+}
+
+// BreakerProvider is implemented by connections guarded by circuit breaker
+type BreakerProvider interface {
+	Breaker() *CircuitBreaker
+}
+
+// GetBreaker returns circuit breaker of provided connection if it is guarded by breaker
+func GetBreaker(conn any) (*CircuitBreaker, bool) {
+	provider, ok := conn.(BreakerProvider)
+	if !ok {
+		return nil, false
+	}
+
+	breaker := provider.Breaker()
+	return breaker, breaker != nil
+}
+
+// CircuitBreaker fails calls fast after connection failed several times in a row.
+//
+// Nil breaker allows every call
+type CircuitBreaker struct {
+	name     string
+	settings BreakerSettings
+
+	mx            sync.Mutex
+	state         BreakerState
+	failures      int
+	successes     int
+	halfOpenCalls int
+	openedAt      time.Time
+}
+
+// NewCircuitBreaker creates circuit breaker in closed state
+func NewCircuitBreaker(name string, settings BreakerSettings) *CircuitBreaker {
+	const (
+		defaultFailureThreshold = 5
+		defaultOpenTimeout      = time.Second * 30
+		defaultHalfOpenMaxCalls = 1
+		defaultSuccessThreshold = 1
+	)
+
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = defaultOpenTimeout
+	}
+
+	if settings.HalfOpenMaxCalls <= 0 {
+		settings.HalfOpenMaxCalls = defaultHalfOpenMaxCalls
+	}
+
+	if settings.SuccessThreshold <= 0 {
+		settings.SuccessThreshold = defaultSuccessThreshold
+	}
+
+	if settings.IsFailure == nil {
+		settings.IsFailure = isBreakerFailure
+	}
+
+	return &CircuitBreaker{
+		name:     name,
+		settings: settings,
+	}
+}
+
+// Name returns name of breaker
+func (b *CircuitBreaker) Name() string {
+	if b == nil {
+		return ""
+	}
+
+	return b.name
+}
+
+// State returns current state of breaker
+func (b *CircuitBreaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.expireOpen()
+	return b.state
+}
+
+// Allow checks if call is allowed. Every allowed call must be finished by Done
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.expireOpen()
+	switch b.state {
+	case BreakerOpen:
+		return b.openError()
+	case BreakerHalfOpen:
+		if b.halfOpenCalls >= b.settings.HalfOpenMaxCalls {
+			return b.openError()
+		}
+
+		b.halfOpenCalls++
+	}
+
+	return nil
+}
+
+// Done reports result of call allowed by Allow
+func (b *CircuitBreaker) Done(err error) {
+	if b == nil {
+		return
+	}
+
+	failure := err != nil && b.settings.IsFailure(err)
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		if !failure {
+			b.failures = 0
+			return
+		}
+
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.setState(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		if b.halfOpenCalls > 0 {
+			b.halfOpenCalls--
+		}
+
+		if failure {
+			b.setState(BreakerOpen)
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.settings.SuccessThreshold {
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+// Execute runs fn if breaker allows call and reports its result
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.Done(err)
+	return err
+}
+
+// expireOpen switches open breaker to half-open state if open timeout is passed
+func (b *CircuitBreaker) expireOpen() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.halfOpenCalls = 0
+	if state == BreakerOpen {
+		b.openedAt = time.Now()
+	}
+
+	if b.settings.OnStateChange != nil && from != state {
+		b.settings.OnStateChange(b.name, from, state)
+	}
+}
+
+func (b *CircuitBreaker) openError() error {
+	return ErrCircuitOpen.
+		SetParams([]errorx.Parameter{
+			{Key: "name", Value: b.name},
+			{Key: "state", Value: b.state.String()},
+		})
+}
+
+func isBreakerFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
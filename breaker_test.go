@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/boostgo/errorx"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errConn := errors.New("connection refused")
+
+	type call struct {
+		// wait before call, for example to pass open timeout
+		wait    time.Duration
+		err     error
+		wantErr error
+	}
+
+	tests := []struct {
+		name      string
+		settings  BreakerSettings
+		calls     []call
+		wantState BreakerState
+	}{
+		{
+			name:      "failures below threshold",
+			settings:  BreakerSettings{FailureThreshold: 3},
+			calls:     []call{{err: errConn, wantErr: errConn}, {err: errConn, wantErr: errConn}},
+			wantState: BreakerClosed,
+		},
+		{
+			name:     "success resets failures",
+			settings: BreakerSettings{FailureThreshold: 2},
+			calls: []call{
+				{err: errConn, wantErr: errConn},
+				{},
+				{err: errConn, wantErr: errConn},
+			},
+			wantState: BreakerClosed,
+		},
+		{
+			name:     "failures reach threshold",
+			settings: BreakerSettings{FailureThreshold: 2},
+			calls: []call{
+				{err: errConn, wantErr: errConn},
+				{err: errConn, wantErr: errConn},
+				{wantErr: ErrCircuitOpen},
+			},
+			wantState: BreakerOpen,
+		},
+		{
+			name:     "canceled calls are not failures",
+			settings: BreakerSettings{FailureThreshold: 1},
+			calls: []call{
+				{err: context.Canceled, wantErr: context.Canceled},
+			},
+			wantState: BreakerClosed,
+		},
+		{
+			name: "custom failures",
+			settings: BreakerSettings{
+				FailureThreshold: 1,
+				IsFailure: func(err error) bool {
+					return !errors.Is(err, errConn)
+				},
+			},
+			calls:     []call{{err: errConn, wantErr: errConn}},
+			wantState: BreakerClosed,
+		},
+		{
+			name:     "half-open after timeout",
+			settings: BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Millisecond},
+			calls: []call{
+				{err: errConn, wantErr: errConn},
+				{wait: time.Millisecond * 5, err: errConn, wantErr: errConn},
+				{wantErr: ErrCircuitOpen},
+			},
+			wantState: BreakerOpen,
+		},
+		{
+			name:     "successful trials close breaker",
+			settings: BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Millisecond, SuccessThreshold: 2},
+			calls: []call{
+				{err: errConn, wantErr: errConn},
+				{wait: time.Millisecond * 5},
+				{},
+				{err: errConn, wantErr: errConn},
+			},
+			wantState: BreakerOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker("sql/a", tt.settings)
+			for idx, c := range tt.calls {
+				time.Sleep(c.wait)
+
+				err := breaker.Execute(func() error {
+					return c.err
+				})
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("call %d: expected error %v, got %v", idx, c.wantErr, err)
+				}
+			}
+
+			if state := breaker.State(); state != tt.wantState {
+				t.Fatalf("expected state %s, got %s", tt.wantState, state)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenCalls(t *testing.T) {
+	var changes []string
+	breaker := NewCircuitBreaker("sql/a", BreakerSettings{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Millisecond,
+		HalfOpenMaxCalls: 1,
+		OnStateChange: func(name string, from, to BreakerState) {
+			changes = append(changes, name+":"+from.String()+"->"+to.String())
+		},
+	})
+
+	breaker.Done(errors.New("connection refused"))
+	time.Sleep(time.Millisecond * 5)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("expected trial call to be allowed, got %v", err)
+	}
+
+	err := breaker.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected second trial call to be rejected, got %v", err)
+	}
+
+	params := make(map[string]any)
+	for _, param := range err.(*errorx.Error).Params() {
+		params[param.Key] = param.Value
+	}
+	if params["name"] != "sql/a" || params["state"] != "half_open" {
+		t.Fatalf("expected name & state params, got %v", params)
+	}
+
+	breaker.Done(nil)
+
+	want := []string{"sql/a:closed->open", "sql/a:open->half_open", "sql/a:half_open->closed"}
+	if !slices.Equal(changes, want) {
+		t.Fatalf("expected state changes %v, got %v", want, changes)
+	}
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	var breaker *CircuitBreaker
+	if err := breaker.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected nil breaker to allow call, got %v", err)
+	}
+
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("expected nil breaker to be closed")
+	}
+}
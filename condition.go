@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const shardValuesKey = "STORAGE_SHARD_VALUES"
+
+// Operators of shard conditions
+const (
+	conditionEqual    = "="
+	conditionNotEqual = "!="
+	conditionPrefix   = "^="
+	conditionGlob     = "~="
+	conditionIn       = "in"
+	conditionNotIn    = "not in"
+)
+
+// WithShardValue sets to new context named value which is matched with shard conditions by ConditionSelector
+func WithShardValue(ctx context.Context, name, value string) context.Context {
+	values, _ := ctx.Value(shardValuesKey).(map[string]string)
+
+	newValues := make(map[string]string, len(values)+1)
+	maps.Copy(newValues, values)
+	newValues[name] = value
+
+	return context.WithValue(ctx, shardValuesKey, newValues)
+}
+
+// GetShardValue returns named value set by WithShardValue
+func GetShardValue(ctx context.Context, name string) (string, bool) {
+	values, _ := ctx.Value(shardValuesKey).(map[string]string)
+	value, ok := values[name]
+	return value, ok
+}
+
+// ConditionalShard is shard with routing conditions
+type ConditionalShard interface {
+	Shard
+	Conditions() []string
+}
+
+// ConditionSelector chooses the first shard which all conditions match values from context (see WithShardValue).
+//
+// Supported conditions:
+//   - "name=value" and "name!=value" - value is equal or not equal;
+//   - "name in (a,b)" and "name not in (a,b)" - value is one of the list or not;
+//   - "name^=prefix" - value starts with prefix;
+//   - "name~=glob" - value matches glob pattern (path.Match syntax).
+//
+// Shard without conditions is never matched. If no shard matched, shard with fallback key is chosen.
+// Empty fallback means there is no fallback shard
+func ConditionSelector[T ConditionalShard](fallback string) Selector[T] {
+	var parsed sync.Map
+
+	return func(ctx context.Context, shards []T) T {
+		for _, shard := range shards {
+			conditions := shard.Conditions()
+			if len(conditions) == 0 {
+				continue
+			}
+
+			matched := true
+			for _, expression := range conditions {
+				cond, err := cachedCondition(&parsed, expression)
+				if err != nil {
+					FailSelection(ctx, err)
+
+					var zero T
+					return zero
+				}
+
+				if !cond.match(ctx) {
+					matched = false
+					break
+				}
+			}
+
+			if matched {
+				return shard
+			}
+		}
+
+		if fallback != "" {
+			return findShard(ctx, shards, fallback)
+		}
+
+		FailSelection(ctx, ErrShardNotFound)
+
+		var zero T
+		return zero
+	}
+}
+
+// ValidateConditions checks if all conditions can be parsed
+func ValidateConditions(conditions []string) error {
+	for _, expression := range conditions {
+		if _, err := parseCondition(expression); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type condition struct {
+	name     string
+	operator string
+	values   []string
+}
+
+func cachedCondition(cache *sync.Map, expression string) (condition, error) {
+	if cached, ok := cache.Load(expression); ok {
+		return cached.(condition), nil
+	}
+
+	cond, err := parseCondition(expression)
+	if err != nil {
+		return condition{}, err
+	}
+
+	cache.Store(expression, cond)
+	return cond, nil
+}
+
+// parseCondition parses condition expression
+func parseCondition(expression string) (condition, error) {
+	invalid := func() (condition, error) {
+		return condition{}, ErrConditionInvalid.AddParam("condition", expression)
+	}
+
+	expression = strings.TrimSpace(expression)
+
+	// list operators: "name in (a,b)" & "name not in (a,b)"
+	lower := strings.ToLower(expression)
+	for _, operator := range []string{conditionNotIn, conditionIn} {
+		idx := strings.Index(lower, " "+operator+" ")
+		if idx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(expression[:idx])
+		list := strings.TrimSpace(expression[idx+len(operator)+2:])
+		if name == "" || !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+			return invalid()
+		}
+
+		values := strings.Split(list[1:len(list)-1], ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+
+		return condition{
+			name:     name,
+			operator: operator,
+			values:   values,
+		}, nil
+	}
+
+	// comparison operators. Two-char operators are checked first because they contain "="
+	for _, operator := range []string{conditionNotEqual, conditionPrefix, conditionGlob, conditionEqual} {
+		idx := strings.Index(expression, operator)
+		if idx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(expression[:idx])
+		value := strings.TrimSpace(expression[idx+len(operator):])
+		if name == "" {
+			return invalid()
+		}
+
+		if operator == conditionGlob {
+			if _, err := path.Match(value, ""); err != nil {
+				return invalid()
+			}
+		}
+
+		return condition{
+			name:     name,
+			operator: operator,
+			values:   []string{value},
+		}, nil
+	}
+
+	return invalid()
+}
+
+// match checks if value from context satisfies condition. Missing value satisfies only negative conditions
+func (c condition) match(ctx context.Context) bool {
+	value, ok := GetShardValue(ctx, c.name)
+
+	switch c.operator {
+	case conditionEqual:
+		return ok && value == c.values[0]
+	case conditionNotEqual:
+		return !ok || value != c.values[0]
+	case conditionPrefix:
+		return ok && strings.HasPrefix(value, c.values[0])
+	case conditionGlob:
+		if !ok {
+			return false
+		}
+
+		matched, _ := path.Match(c.values[0], value)
+		return matched
+	case conditionIn:
+		return ok && slices.Contains(c.values, value)
+	case conditionNotIn:
+		return !ok || !slices.Contains(c.values, value)
+	default:
+		return false
+	}
+}
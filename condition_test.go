@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       condition
+		wantErr    bool
+	}{
+		{expression: "region=eu", want: condition{name: "region", operator: conditionEqual, values: []string{"eu"}}},
+		{expression: " region = eu ", want: condition{name: "region", operator: conditionEqual, values: []string{"eu"}}},
+		{expression: "region!=eu", want: condition{name: "region", operator: conditionNotEqual, values: []string{"eu"}}},
+		{expression: "tenant^=acme-", want: condition{name: "tenant", operator: conditionPrefix, values: []string{"acme-"}}},
+		{expression: "tenant~=acme-*", want: condition{name: "tenant", operator: conditionGlob, values: []string{"acme-*"}}},
+		{expression: "region in (eu, us)", want: condition{name: "region", operator: conditionIn, values: []string{"eu", "us"}}},
+		{expression: "region IN (eu)", want: condition{name: "region", operator: conditionIn, values: []string{"eu"}}},
+		{expression: "region not in (eu,us)", want: condition{name: "region", operator: conditionNotIn, values: []string{"eu", "us"}}},
+		{expression: "region", wantErr: true},
+		{expression: "=eu", wantErr: true},
+		{expression: "region in eu", wantErr: true},
+		{expression: " in (eu)", wantErr: true},
+		{expression: "tenant~=[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := parseCondition(tt.expression)
+			if tt.wantErr {
+				if !errors.Is(err, ErrConditionInvalid) {
+					t.Fatalf("expected invalid condition, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got.name != tt.want.name || got.operator != tt.want.operator || !slices.Equal(got.values, tt.want.values) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	tests := []struct {
+		expression string
+		// value of "tenant", nil means value is not set
+		value *string
+		want  bool
+	}{
+		{expression: "tenant=acme", value: ptr("acme"), want: true},
+		{expression: "tenant=acme", value: ptr("globex"), want: false},
+		{expression: "tenant=acme", value: nil, want: false},
+		{expression: "tenant!=acme", value: ptr("globex"), want: true},
+		{expression: "tenant!=acme", value: nil, want: true},
+		{expression: "tenant^=ac", value: ptr("acme"), want: true},
+		{expression: "tenant^=ac", value: nil, want: false},
+		{expression: "tenant~=a*e", value: ptr("acme"), want: true},
+		{expression: "tenant~=a*e", value: ptr("acmes"), want: false},
+		{expression: "tenant in (acme, globex)", value: ptr("globex"), want: true},
+		{expression: "tenant in (acme, globex)", value: nil, want: false},
+		{expression: "tenant not in (acme, globex)", value: ptr("initech"), want: true},
+		{expression: "tenant not in (acme, globex)", value: nil, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			cond, err := parseCondition(tt.expression)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			ctx := context.Background()
+			if tt.value != nil {
+				ctx = WithShardValue(ctx, "tenant", *tt.value)
+			}
+
+			if got := cond.match(ctx); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConditionSelector(t *testing.T) {
+	shards := []*testShard{
+		{key: "eu-premium", conditions: []string{"region=eu", "plan in (pro, enterprise)"}},
+		{key: "eu", conditions: []string{"region=eu"}},
+		{key: "default"},
+	}
+
+	tests := []struct {
+		name      string
+		fallback  string
+		shards    []*testShard
+		values    map[string]string
+		wantShard string
+		wantErr   error
+	}{
+		{name: "all conditions match", values: map[string]string{"region": "eu", "plan": "pro"}, wantShard: "eu-premium"},
+		{name: "first matched shard", values: map[string]string{"region": "eu", "plan": "free"}, wantShard: "eu"},
+		{name: "fallback", fallback: "default", values: map[string]string{"region": "us"}, wantShard: "default"},
+		{name: "shard without conditions is not matched", values: map[string]string{"region": "us"}, wantErr: ErrShardNotFound},
+		{name: "missing fallback shard", fallback: "us", values: map[string]string{"region": "us"}, wantErr: ErrShardNotFound},
+		{
+			name:    "invalid condition",
+			shards:  []*testShard{{key: "broken", conditions: []string{"region"}}},
+			wantErr: ErrConditionInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			for name, value := range tt.values {
+				ctx = WithShardValue(ctx, name, value)
+			}
+			ctx, selectionErr := TrackSelection(ctx)
+
+			selectFrom := shards
+			if tt.shards != nil {
+				selectFrom = tt.shards
+			}
+
+			var shard string
+			if selected := ConditionSelector[*testShard](tt.fallback)(ctx, selectFrom); selected != nil {
+				shard = selected.Key()
+			}
+
+			if err := selectionErr(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if shard != tt.wantShard {
+				t.Fatalf("expected shard %q, got %q", tt.wantShard, shard)
+			}
+		})
+	}
+}
+
+func TestValidateConditions(t *testing.T) {
+	if err := ValidateConditions([]string{"region=eu", "plan in (pro)"}); err != nil {
+		t.Fatalf("expected valid conditions, got %v", err)
+	}
+
+	if err := ValidateConditions([]string{"region=eu", "plan"}); !errors.Is(err, ErrConditionInvalid) {
+		t.Fatalf("expected invalid condition, got %v", err)
+	}
+}
+
+func TestWithShardValue(t *testing.T) {
+	parent := WithShardValue(context.Background(), "region", "eu")
+	child := WithShardValue(parent, "plan", "pro")
+
+	if _, ok := GetShardValue(parent, "plan"); ok {
+		t.Fatal("expected parent context not to be changed")
+	}
+
+	region, _ := GetShardValue(child, "region")
+	plan, _ := GetShardValue(child, "plan")
+	if region != "eu" || plan != "pro" {
+		t.Fatalf("expected both values in child context, got %q & %q", region, plan)
+	}
+}
+
+func ptr(value string) *string {
+	return &value
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// Drainer counts in-flight calls of shard connection, so connection removed from shards
+// can be closed only after all started calls are finished.
+//
+// Zero value is ready to use
+type Drainer struct {
+	mx       sync.Mutex
+	inFlight int
+	draining bool
+	done     chan struct{}
+}
+
+// Acquire registers new call. Returns false if connection is draining and call must not be started
+func (d *Drainer) Acquire() bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if d.draining {
+		return false
+	}
+
+	d.inFlight++
+	return true
+}
+
+// Join registers call which belongs to already started call, for example query of in-flight transaction.
+//
+// Unlike Acquire, call is registered even if connection is draining, until all started calls are finished.
+// Returns false if connection is drained
+func (d *Drainer) Join() bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if d.draining && d.inFlight == 0 {
+		return false
+	}
+
+	d.inFlight++
+	return true
+}
+
+// Release finishes call registered by Acquire or Join
+func (d *Drainer) Release() {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.inFlight--
+	if d.draining && d.inFlight == 0 && d.done != nil {
+		close(d.done)
+		d.done = nil
+	}
+}
+
+// InFlight returns count of started calls
+func (d *Drainer) InFlight() int {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	return d.inFlight
+}
+
+// Drain rejects new calls and waits for started calls to finish or for context to be done
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mx.Lock()
+	d.draining = true
+	if d.inFlight == 0 {
+		d.mx.Unlock()
+		return nil
+	}
+
+	if d.done == nil {
+		d.done = make(chan struct{})
+	}
+	done := d.done
+	d.mx.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ErrDrainTimeout.SetError(ctx.Err())
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	tests := []struct {
+		name string
+		// acquired is count of calls started before drain
+		acquired int
+		// released is count of calls finished while drain waits
+		released int
+		timeout  time.Duration
+		wantErr  error
+	}{
+		{name: "no calls", timeout: time.Second},
+		{name: "calls finished", acquired: 2, released: 2, timeout: time.Second},
+		{name: "call not finished", acquired: 2, released: 1, timeout: 20 * time.Millisecond, wantErr: ErrDrainTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var drainer Drainer
+			for range tt.acquired {
+				if !drainer.Acquire() {
+					t.Fatal("expected call to be acquired")
+				}
+			}
+
+			if got := drainer.InFlight(); got != tt.acquired {
+				t.Fatalf("expected %d calls in flight, got %d", tt.acquired, got)
+			}
+
+			go func() {
+				for range tt.released {
+					time.Sleep(time.Millisecond)
+					drainer.Release()
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			if err := drainer.Drain(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if drainer.Acquire() {
+				t.Fatal("expected drained drainer to reject calls")
+			}
+		})
+	}
+}
+
+func TestDrainerJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		acquired int
+		draining bool
+		want     bool
+	}{
+		{name: "not draining", want: true},
+		{name: "draining with started calls", acquired: 1, draining: true, want: true},
+		{name: "drained", draining: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var drainer Drainer
+			for range tt.acquired {
+				drainer.Acquire()
+			}
+
+			drained := make(chan error, 1)
+			if tt.draining {
+				go func() {
+					drained <- drainer.Drain(context.Background())
+				}()
+
+				// wait until drain rejects new calls
+				for drainer.Acquire() {
+					drainer.Release()
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			if got := drainer.Join(); got != tt.want {
+				t.Fatalf("expected join %v, got %v", tt.want, got)
+			}
+
+			// release joined & started calls, so drain is finished
+			if tt.want {
+				drainer.Release()
+			}
+			for range tt.acquired {
+				drainer.Release()
+			}
+
+			if tt.draining {
+				if err := <-drained; err != nil {
+					t.Fatalf("drain: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -4,5 +4,55 @@ import (
 	"github.com/boostgo/errorx"
 )
 
-// ErrConnNotSelected returns if "shard client" does not choose connection to use
-var ErrConnNotSelected = errorx.New("sql.connection_not_selected")
+var (
+	// ErrConnNotSelected returns if "shard client" does not choose connection to use
+	ErrConnNotSelected = errorx.New("sql.connection_not_selected")
+	// ErrShardKeyMissing returns if selector requires shard key but context does not contain it (see WithShardKey)
+	ErrShardKeyMissing = errorx.New("storage.shard_key_missing")
+	// ErrShardKeyInvalid returns if shard key can not be used by selector, for example it is not numeric
+	ErrShardKeyInvalid = errorx.New("storage.shard_key_invalid")
+	// ErrShardNotFound returns if there is no shard for shard key
+	ErrShardNotFound = errorx.New("storage.shard_not_found")
+	// ErrConditionInvalid returns if shard condition can not be parsed
+	ErrConditionInvalid = errorx.New("storage.condition_invalid")
+	// ErrShardExists returns if shard with the same key is already added
+	ErrShardExists = errorx.New("storage.shard_exists")
+	// ErrShardRemoved returns if selected shard was removed or replaced and does not accept calls anymore
+	ErrShardRemoved = errorx.New("storage.shard_removed")
+	// ErrDrainTimeout returns if in-flight calls of removed shard were not finished before context is done
+	ErrDrainTimeout = errorx.New("storage.drain_timeout")
+	// ErrReloadNotSupported returns if shards were not created from configs and can not be reloaded
+	ErrReloadNotSupported = errorx.New("storage.reload_not_supported")
+
+	// ErrTransactionRequired returns if PropagationMandatory is used without existing transaction
+	ErrTransactionRequired = errorx.New("storage.transaction_required")
+	// ErrTransactionNotAllowed returns if PropagationNever is used inside existing transaction
+	ErrTransactionNotAllowed = errorx.New("storage.transaction_not_allowed")
+	// ErrTransactionRollbackOnly returns on commit if one of joined participants rolled transaction back
+	ErrTransactionRollbackOnly = errorx.New("storage.transaction_rollback_only")
+	// ErrTransactionNotOwned returns if participant which joined transaction set by SetTx tries to roll it back.
+	// Transaction stays alive and must be rolled back by the code which set it
+	ErrTransactionNotOwned = errorx.New("storage.transaction_not_owned")
+
+	// ErrTwoPhasePrepare returns if one of transactions failed to prepare and all transactions were rolled back
+	ErrTwoPhasePrepare = errorx.New("storage.two_phase_prepare")
+	// ErrTwoPhaseCommit returns if prepared transaction failed to commit while others may be already committed.
+	// Such transaction stays prepared until it is resolved by saved commit decision, so transaction hooks do not run
+	ErrTwoPhaseCommit = errorx.New("storage.two_phase_commit")
+	// ErrTwoPhaseDecision returns if commit decision can not be saved to DecisionStore and all transactions were rolled back
+	ErrTwoPhaseDecision = errorx.New("storage.two_phase_decision")
+	// ErrDecisionStoreMissing returns if two-phase commit is run without DecisionStore
+	ErrDecisionStoreMissing = errorx.New("storage.decision_store_missing")
+
+	// ErrSagaStep returns if saga step failed and done steps were compensated
+	ErrSagaStep = errorx.New("storage.saga_step")
+	// ErrSagaCompensation returns if saga step compensation failed
+	ErrSagaCompensation = errorx.New("storage.saga_compensation")
+	// ErrSagaSave returns if saga state can not be saved to store
+	ErrSagaSave = errorx.New("storage.saga_save")
+	// ErrSagaClaim returns if pending saga can not be claimed for recovery
+	ErrSagaClaim = errorx.New("storage.saga_claim")
+
+	// ErrCircuitOpen returns if circuit breaker of connection is open and call is rejected without trying
+	ErrCircuitOpen = errorx.New("storage.circuit_open")
+)
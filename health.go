@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Probe checks availability of single connection
+type Probe struct {
+	// Name is unique name of probe, for example "sql/shard_1"
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+// ProbeSource returns current probes, for example probes of shards which can be added or removed at runtime
+type ProbeSource func() []Probe
+
+// HealthStatus is result of the last checks of probe
+type HealthStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	// Checked is false until probe is checked first time
+	Checked             bool          `json:"checked"`
+	Latency             time.Duration `json:"latency"`
+	LastError           string        `json:"last_error,omitempty"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	CheckedAt           time.Time     `json:"checked_at"`
+}
+
+// HealthOption sets health checker settings
+type HealthOption func(health *Health)
+
+// HealthIntervalOption sets delay between background checks
+func HealthIntervalOption(interval time.Duration) HealthOption {
+	return func(health *Health) {
+		if interval <= 0 {
+			return
+		}
+
+		health.interval = interval
+	}
+}
+
+// HealthTimeoutOption sets timeout of single probe ping
+func HealthTimeoutOption(timeout time.Duration) HealthOption {
+	return func(health *Health) {
+		if timeout <= 0 {
+			return
+		}
+
+		health.timeout = timeout
+	}
+}
+
+// HealthFailureThresholdOption sets count of consecutive failures after which probe becomes unhealthy
+func HealthFailureThresholdOption(threshold int) HealthOption {
+	return func(health *Health) {
+		if threshold <= 0 {
+			return
+		}
+
+		health.failureThreshold = threshold
+	}
+}
+
+// Health is registry of probes which are checked periodically by Run or on demand by Check
+type Health struct {
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+
+	mx       sync.RWMutex
+	probes   []Probe
+	statuses map[string]*HealthStatus
+	sources  []*probeSource
+}
+
+// probeSource is registered ProbeSource with names of probes it returned last time
+type probeSource struct {
+	source ProbeSource
+	names  []string
+}
+
+// NewHealth creates health checker
+func NewHealth(options ...HealthOption) *Health {
+	const (
+		defaultInterval         = time.Second * 10
+		defaultTimeout          = time.Second * 2
+		defaultFailureThreshold = 1
+	)
+
+	health := &Health{
+		interval:         defaultInterval,
+		timeout:          defaultTimeout,
+		failureThreshold: defaultFailureThreshold,
+		statuses:         make(map[string]*HealthStatus),
+	}
+
+	for _, option := range options {
+		option(health)
+	}
+
+	return health
+}
+
+// Register adds probes to health checker. Probe with already registered name replaces the old one
+func (h *Health) Register(probes ...Probe) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.register(probes)
+}
+
+// Unregister removes probes by names. Removed probes do not affect readiness anymore
+func (h *Health) Unregister(names ...string) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.unregister(names)
+}
+
+// RegisterSource adds source of probes which is called at once and before every check.
+// New probes of source are registered and probes missing since previous call are unregistered.
+//
+// For example, source of sql.Connections.Probes follows shards added or removed at runtime
+func (h *Health) RegisterSource(source ProbeSource) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	registered := &probeSource{
+		source: source,
+	}
+	h.sources = append(h.sources, registered)
+	h.syncSource(registered)
+}
+
+func (h *Health) register(probes []Probe) {
+	for _, probe := range probes {
+		h.setProbe(probe)
+		h.statuses[probe.Name] = &HealthStatus{
+			Name: probe.Name,
+		}
+	}
+}
+
+// setProbe replaces registered probe with the same name keeping its status or adds new probe
+func (h *Health) setProbe(probe Probe) {
+	idx := slices.IndexFunc(h.probes, func(registered Probe) bool {
+		return registered.Name == probe.Name
+	})
+	if idx == -1 {
+		h.probes = append(h.probes, probe)
+		h.statuses[probe.Name] = &HealthStatus{
+			Name: probe.Name,
+		}
+		return
+	}
+
+	h.probes[idx] = probe
+}
+
+func (h *Health) unregister(names []string) {
+	h.probes = slices.DeleteFunc(h.probes, func(probe Probe) bool {
+		return slices.Contains(names, probe.Name)
+	})
+
+	for _, name := range names {
+		delete(h.statuses, name)
+	}
+}
+
+// syncSources calls all sources and applies their probes
+func (h *Health) syncSources() {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	for _, source := range h.sources {
+		h.syncSource(source)
+	}
+}
+
+// syncSource registers probes returned by source and unregisters probes which source does not return anymore.
+// Probes which are returned again keep their statuses
+func (h *Health) syncSource(source *probeSource) {
+	probes := source.source()
+
+	names := make([]string, 0, len(probes))
+	for _, probe := range probes {
+		names = append(names, probe.Name)
+		h.setProbe(probe)
+	}
+
+	removed := slices.DeleteFunc(source.names, func(name string) bool {
+		return slices.Contains(names, name)
+	})
+	h.unregister(removed)
+	source.names = names
+}
+
+// Run checks all probes every interval until context is done
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pings all probes in parallel and updates their statuses
+func (h *Health) Check(ctx context.Context) {
+	h.syncSources()
+
+	h.mx.RLock()
+	probes := make([]Probe, len(h.probes))
+	copy(probes, h.probes)
+	h.mx.RUnlock()
+
+	wg := errgroup.Group{}
+	for _, probe := range probes {
+		wg.Go(func() error {
+			h.check(ctx, probe)
+			return nil
+		})
+	}
+	_ = wg.Wait()
+}
+
+func (h *Health) check(ctx context.Context, probe Probe) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := probe.Ping(ctx)
+	latency := time.Since(start)
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	status, ok := h.statuses[probe.Name]
+	if !ok {
+		// probe was removed while it was checked
+		return
+	}
+
+	status.Checked = true
+	status.Latency = latency
+	status.CheckedAt = start
+	if err != nil {
+		status.LastError = err.Error()
+		status.ConsecutiveFailures++
+	} else {
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+	}
+	status.Healthy = status.ConsecutiveFailures < h.failureThreshold
+}
+
+// Status returns statuses of all probes in registration order
+func (h *Health) Status() []HealthStatus {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	statuses := make([]HealthStatus, 0, len(h.probes))
+	for _, probe := range h.probes {
+		statuses = append(statuses, *h.statuses[probe.Name])
+	}
+
+	return statuses
+}
+
+// Ready checks if all probes are checked and healthy
+func (h *Health) Ready() bool {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	for _, status := range h.statuses {
+		if !status.Checked || !status.Healthy {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LivenessHandler returns handler which always responds with 200 and statuses of probes.
+//
+// Liveness does not depend on storages, so unavailable shard does not restart the service
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		h.writeStatus(w, http.StatusOK)
+	})
+}
+
+// ReadinessHandler returns handler which responds with 200 if all probes are healthy or 503 otherwise
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		code := http.StatusOK
+		if !h.Ready() {
+			code = http.StatusServiceUnavailable
+		}
+
+		h.writeStatus(w, code)
+	})
+}
+
+func (h *Health) writeStatus(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(h.Status())
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func pingProbe(name string, err error) Probe {
+	return Probe{
+		Name: name,
+		Ping: func(context.Context) error {
+			return err
+		},
+	}
+}
+
+func statusNames(health *Health) []string {
+	names := make([]string, 0)
+	for _, status := range health.Status() {
+		names = append(names, status.Name)
+	}
+
+	return names
+}
+
+func TestHealthCheck(t *testing.T) {
+	errPing := errors.New("ping failed")
+
+	tests := []struct {
+		name        string
+		threshold   int
+		probes      []Probe
+		checks      int
+		unregister  []string
+		wantReady   bool
+		wantHealthy map[string]bool
+	}{
+		{
+			name:        "not checked",
+			probes:      []Probe{pingProbe("sql/a", nil)},
+			wantHealthy: map[string]bool{"sql/a": false},
+		},
+		{
+			name:        "healthy",
+			probes:      []Probe{pingProbe("sql/a", nil), pingProbe("redis/a", nil)},
+			checks:      1,
+			wantReady:   true,
+			wantHealthy: map[string]bool{"sql/a": true, "redis/a": true},
+		},
+		{
+			name:        "failed probe",
+			probes:      []Probe{pingProbe("sql/a", nil), pingProbe("sql/b", errPing)},
+			checks:      1,
+			wantHealthy: map[string]bool{"sql/a": true, "sql/b": false},
+		},
+		{
+			name:        "failures below threshold",
+			threshold:   3,
+			probes:      []Probe{pingProbe("sql/a", errPing)},
+			checks:      2,
+			wantReady:   true,
+			wantHealthy: map[string]bool{"sql/a": true},
+		},
+		{
+			name:        "failures reach threshold",
+			threshold:   3,
+			probes:      []Probe{pingProbe("sql/a", errPing)},
+			checks:      3,
+			wantHealthy: map[string]bool{"sql/a": false},
+		},
+		{
+			name:        "unregistered failed probe",
+			probes:      []Probe{pingProbe("sql/a", nil), pingProbe("sql/b", errPing)},
+			checks:      1,
+			unregister:  []string{"sql/b"},
+			wantReady:   true,
+			wantHealthy: map[string]bool{"sql/a": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealth(HealthFailureThresholdOption(tt.threshold))
+			health.Register(tt.probes...)
+			for range tt.checks {
+				health.Check(context.Background())
+			}
+			health.Unregister(tt.unregister...)
+
+			if ready := health.Ready(); ready != tt.wantReady {
+				t.Fatalf("expected ready %v, got %v", tt.wantReady, ready)
+			}
+
+			statuses := health.Status()
+			if len(statuses) != len(tt.wantHealthy) {
+				t.Fatalf("expected %d statuses, got %v", len(tt.wantHealthy), statuses)
+			}
+
+			for _, status := range statuses {
+				if status.Healthy != tt.wantHealthy[status.Name] {
+					t.Fatalf("expected %s healthy %v, got %v", status.Name, tt.wantHealthy[status.Name], status.Healthy)
+				}
+			}
+
+			code := httptest.NewRecorder()
+			health.ReadinessHandler().ServeHTTP(code, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			wantCode := http.StatusServiceUnavailable
+			if tt.wantReady {
+				wantCode = http.StatusOK
+			}
+			if code.Code != wantCode {
+				t.Fatalf("expected readiness code %d, got %d", wantCode, code.Code)
+			}
+		})
+	}
+}
+
+func TestHealthSource(t *testing.T) {
+	errPing := errors.New("ping failed")
+
+	probes := []Probe{pingProbe("sql/a", nil), pingProbe("sql/b", errPing)}
+	health := NewHealth()
+	health.Register(pingProbe("redis/a", nil))
+	health.RegisterSource(func() []Probe {
+		return probes
+	})
+
+	if names := statusNames(health); !slices.Equal(names, []string{"redis/a", "sql/a", "sql/b"}) {
+		t.Fatalf("expected source probes to be registered at once, got %v", names)
+	}
+
+	health.Check(context.Background())
+	if health.Ready() {
+		t.Fatal("expected not ready with failed shard")
+	}
+
+	// shard "b" is removed, shard "c" is added
+	probes = []Probe{pingProbe("sql/a", nil), pingProbe("sql/c", nil)}
+	health.Check(context.Background())
+
+	if names := statusNames(health); !slices.Equal(names, []string{"redis/a", "sql/a", "sql/c"}) {
+		t.Fatalf("expected removed shard to be unregistered, got %v", names)
+	}
+
+	if !health.Ready() {
+		t.Fatalf("expected ready, got %v", health.Status())
+	}
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/log"
+)
+
+const (
+	hooksKey          = "STORAGE_TX_HOOKS"
+	hooksLockKey      = "STORAGE_TX_HOOKS_LOCK"
+	hooksOwnerKey     = "STORAGE_TX_HOOKS_OWNER"
+	hookEventCommit   = "commit"
+	hookEventRollback = "rollback"
+)
+
+// Hook is callback which runs after transaction commit or rollback.
+//
+// Provided context is the context transaction began with
+type Hook func(ctx context.Context) error
+
+// OnCommit registers hook which runs after the outermost transaction from context is committed.
+//
+// If context does not contain transaction, hook runs immediately and its error is returned
+func OnCommit(ctx context.Context, hook Hook) error {
+	hooks, ok := GetHooks(ctx)
+	if !ok {
+		return hook(ctx)
+	}
+
+	hooks.mx.Lock()
+	defer hooks.mx.Unlock()
+
+	hooks.commit = append(hooks.commit, hook)
+	return nil
+}
+
+// OnRollback registers hook which runs after the outermost transaction from context is rolled back.
+//
+// If context does not contain transaction, there is nothing to roll back, so hook is ignored and nil is returned
+func OnRollback(ctx context.Context, hook Hook) error {
+	hooks, ok := GetHooks(ctx)
+	if !ok {
+		return nil
+	}
+
+	hooks.mx.Lock()
+	defer hooks.mx.Unlock()
+
+	hooks.rollback = append(hooks.rollback, hook)
+	return nil
+}
+
+// Hooks contain commit & rollback hooks registered inside transaction.
+//
+// Hooks are created by Transactor implementations which begin new transaction (see BeginHooks)
+// and run in registration order only once
+type Hooks struct {
+	ctx      context.Context
+	mx       sync.Mutex
+	commit   []Hook
+	rollback []Hook
+}
+
+// GetHooks returns hooks of transaction from context if they exist
+func GetHooks(ctx context.Context) (*Hooks, bool) {
+	hooks, ok := ctx.Value(hooksKey).(*Hooks)
+	return hooks, ok && hooks != nil
+}
+
+// BeginHooks creates hooks for new transaction and sets them to new context.
+//
+// Returns nil hooks if transaction is started by composite transactor, which runs hooks by itself.
+// Transactor must run returned hooks after commit or rollback of transaction
+func BeginHooks(ctx context.Context) (context.Context, *Hooks) {
+	if hooks, ok := GetHooks(ctx); ok && ctx.Value(hooksLockKey) == hooks {
+		return ctx, nil
+	}
+
+	hooks := &Hooks{
+		ctx: ctx,
+	}
+
+	return context.WithValue(ctx, hooksKey, hooks), hooks
+}
+
+// RunCommit runs commit hooks. Rollback hooks are dropped.
+//
+// Hook errors & panics are logged and do not affect other hooks
+func (h *Hooks) RunCommit() {
+	if h == nil {
+		return
+	}
+
+	h.run(hookEventCommit)
+}
+
+// RunRollback runs rollback hooks. Commit hooks are dropped.
+//
+// Hook errors & panics are logged and do not affect other hooks
+func (h *Hooks) RunRollback() {
+	if h == nil {
+		return
+	}
+
+	h.run(hookEventRollback)
+}
+
+// Mark returns current count of commit hooks.
+//
+// Used with DiscardFrom by nested transactions (savepoints)
+func (h *Hooks) Mark() int {
+	if h == nil {
+		return 0
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	return len(h.commit)
+}
+
+// DiscardFrom drops commit hooks registered after provided mark.
+//
+// Used when nested transaction (savepoint) is rolled back, so its commit hooks must not run
+func (h *Hooks) DiscardFrom(mark int) {
+	if h == nil {
+		return
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if mark < len(h.commit) {
+		h.commit = h.commit[:mark]
+	}
+}
+
+func (h *Hooks) run(event string) {
+	h.mx.Lock()
+	hooks := h.commit
+	if event == hookEventRollback {
+		hooks = h.rollback
+	}
+	h.commit = nil
+	h.rollback = nil
+	h.mx.Unlock()
+
+	for _, hook := range hooks {
+		err := errorx.Try(func() error {
+			return hook(h.ctx)
+		})
+		if err != nil {
+			log.
+				Error().
+				Ctx(h.ctx).
+				Err(err).
+				Str("event", event).
+				Msg("Transaction hook failed")
+		}
+	}
+}
+
+// lockHooks creates hooks which are run by composite transactor and sets them to new context.
+//
+// Child transactors do not create their own hooks while context is locked.
+// Returns nil hooks if hooks are already locked by outer composite transactor
+func lockHooks(ctx context.Context) (context.Context, *Hooks) {
+	ctx, hooks := BeginHooks(ctx)
+	if hooks == nil {
+		return ctx, nil
+	}
+
+	return context.WithValue(ctx, hooksLockKey, hooks), hooks
+}
+
+// unlockHooks allows nested transactions create their own hooks again
+// and marks provided hooks as owned by composite transactor which began the context
+func unlockHooks(ctx context.Context, hooks *Hooks) context.Context {
+	if hooks != nil {
+		ctx = context.WithValue(ctx, hooksLockKey, nil)
+	}
+
+	return context.WithValue(ctx, hooksOwnerKey, hooks)
+}
+
+// ownedHooks returns hooks if they are owned by composite transactor which began the context
+func ownedHooks(ctx context.Context) *Hooks {
+	owned, _ := ctx.Value(hooksOwnerKey).(*Hooks)
+	if hooks, ok := GetHooks(ctx); !ok || owned != hooks {
+		return nil
+	}
+
+	return owned
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(hooks *Hooks)
+		discardFrom int
+		want        []string
+	}{
+		{
+			name:        "commit runs commit hooks in order",
+			run:         (*Hooks).RunCommit,
+			discardFrom: -1,
+			want:        []string{"commit 1", "commit 2", "commit 3"},
+		},
+		{
+			name:        "rollback runs rollback hooks in order",
+			run:         (*Hooks).RunRollback,
+			discardFrom: -1,
+			want:        []string{"rollback 1", "rollback 2"},
+		},
+		{
+			name:        "discarded commit hooks do not run",
+			run:         (*Hooks).RunCommit,
+			discardFrom: 1,
+			want:        []string{"commit 1"},
+		},
+		{
+			name:        "discard does not affect rollback hooks",
+			run:         (*Hooks).RunRollback,
+			discardFrom: 0,
+			want:        []string{"rollback 1", "rollback 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, hooks := BeginHooks(context.Background())
+
+			var called []string
+			register := func(event, name string) Hook {
+				return func(ctx context.Context) error {
+					called = append(called, event+" "+name)
+					return nil
+				}
+			}
+
+			_ = OnCommit(ctx, register("commit", "1"))
+			_ = OnRollback(ctx, register("rollback", "1"))
+			_ = OnCommit(ctx, register("commit", "2"))
+			_ = OnCommit(ctx, register("commit", "3"))
+			_ = OnRollback(ctx, register("rollback", "2"))
+
+			if tt.discardFrom >= 0 {
+				hooks.DiscardFrom(tt.discardFrom)
+			}
+
+			tt.run(hooks)
+			// hooks run only once
+			tt.run(hooks)
+
+			if !slices.Equal(called, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, called)
+			}
+		})
+	}
+}
+
+func TestHooksFailedHookDoesNotStopOthers(t *testing.T) {
+	ctx, hooks := BeginHooks(context.Background())
+
+	var called int
+	_ = OnCommit(ctx, func(ctx context.Context) error {
+		called++
+		return errors.New("hook failed")
+	})
+	_ = OnCommit(ctx, func(ctx context.Context) error {
+		panic("hook panic")
+	})
+	_ = OnCommit(ctx, func(ctx context.Context) error {
+		called++
+		return nil
+	})
+
+	hooks.RunCommit()
+
+	if called != 2 {
+		t.Fatalf("expected 2 hooks to run, got %d", called)
+	}
+}
+
+func TestHooksWithoutTransaction(t *testing.T) {
+	ctx := context.Background()
+	hookErr := errors.New("hook failed")
+
+	var committed bool
+	err := OnCommit(ctx, func(ctx context.Context) error {
+		committed = true
+		return hookErr
+	})
+	if !committed {
+		t.Fatal("commit hook must run immediately without transaction")
+	}
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+
+	var rolledBack bool
+	err = OnRollback(ctx, func(ctx context.Context) error {
+		rolledBack = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rolledBack {
+		t.Fatal("rollback hook must be ignored without transaction")
+	}
+}
+
+func TestBeginHooksLocked(t *testing.T) {
+	ctx, locked := lockHooks(context.Background())
+	if locked == nil {
+		t.Fatal("expected hooks of composite transactor")
+	}
+
+	if _, hooks := BeginHooks(ctx); hooks != nil {
+		t.Fatal("child transactor must not create hooks while they are locked")
+	}
+
+	ctx = unlockHooks(ctx, locked)
+	if ownedHooks(ctx) != locked {
+		t.Fatal("expected hooks owned by composite transactor")
+	}
+
+	if _, hooks := BeginHooks(ctx); hooks == nil {
+		t.Fatal("nested transaction must create hooks after unlock")
+	}
+}
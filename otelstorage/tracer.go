@@ -0,0 +1,79 @@
+// Package otelstorage adapts OpenTelemetry tracer to storage.Tracer, so storage package does not depend on OpenTelemetry
+package otelstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boostgo/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer creates tracer which starts spans by OpenTelemetry tracer. Set it by storage.SetTracer
+func NewTracer(tracer trace.Tracer) storage.Tracer {
+	return &otelTracer{
+		tracer: tracer,
+	}
+}
+
+func (t *otelTracer) Start(ctx context.Context, name string, attributes ...storage.Attribute) (context.Context, storage.Span) {
+	ctx, span := t.tracer.Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(otelAttributes(attributes)...),
+	)
+
+	return ctx, &otelSpan{
+		span: span,
+	}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attributes ...storage.Attribute) {
+	s.span.SetAttributes(otelAttributes(attributes)...)
+}
+
+func (s *otelSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func otelAttributes(attributes []storage.Attribute) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		switch value := attr.Value.(type) {
+		case string:
+			converted = append(converted, attribute.String(attr.Key, value))
+		case bool:
+			converted = append(converted, attribute.Bool(attr.Key, value))
+		case int:
+			converted = append(converted, attribute.Int(attr.Key, value))
+		case int64:
+			converted = append(converted, attribute.Int64(attr.Key, value))
+		case float64:
+			converted = append(converted, attribute.Float64(attr.Key, value))
+		default:
+			converted = append(converted, attribute.String(attr.Key, fmt.Sprint(value)))
+		}
+	}
+
+	return converted
+}
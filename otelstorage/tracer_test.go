@@ -0,0 +1,36 @@
+package otelstorage
+
+import (
+	"testing"
+
+	"github.com/boostgo/storage"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestOtelAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  attribute.Value
+	}{
+		{name: "string", value: "users", want: attribute.StringValue("users")},
+		{name: "bool", value: true, want: attribute.BoolValue(true)},
+		{name: "int", value: 3, want: attribute.IntValue(3)},
+		{name: "int64", value: int64(4), want: attribute.Int64Value(4)},
+		{name: "float64", value: 1.5, want: attribute.Float64Value(1.5)},
+		{name: "other", value: []int{1, 2}, want: attribute.StringValue("[1 2]")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted := otelAttributes([]storage.Attribute{{Key: "key", Value: tt.value}})
+			if len(converted) != 1 {
+				t.Fatalf("expected 1 attribute, got %d", len(converted))
+			}
+
+			if converted[0].Key != "key" || converted[0].Value != tt.want {
+				t.Fatalf("expected key=%v, got %v=%v", tt.want.Emit(), converted[0].Key, converted[0].Value.Emit())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import "context"
+
+const propagationKey = "STORAGE_TX_PROPAGATION"
+
+// Propagation describes how Transactor begins transaction if context already contain one
+type Propagation int
+
+const (
+	// PropagationDefault means propagation is not chosen and Transactor uses its own default
+	PropagationDefault Propagation = iota
+	// PropagationRequired joins existing transaction or creates new one if it does not exist
+	PropagationRequired
+	// PropagationRequiresNew suspends existing transaction and always creates new independent one
+	PropagationRequiresNew
+	// PropagationSupports joins existing transaction or runs without transaction if it does not exist
+	PropagationSupports
+	// PropagationMandatory joins existing transaction or returns ErrTransactionRequired if it does not exist
+	PropagationMandatory
+	// PropagationNever runs without transaction or returns ErrTransactionNotAllowed if transaction exist
+	PropagationNever
+	// PropagationNested creates nested transaction inside existing one or creates new one if it does not exist
+	PropagationNested
+)
+
+// String returns name of propagation
+func (p Propagation) String() string {
+	switch p {
+	case PropagationRequired:
+		return "required"
+	case PropagationRequiresNew:
+		return "requires_new"
+	case PropagationSupports:
+		return "supports"
+	case PropagationMandatory:
+		return "mandatory"
+	case PropagationNever:
+		return "never"
+	case PropagationNested:
+		return "nested"
+	default:
+		return "default"
+	}
+}
+
+// SetPropagation sets propagation to new context.
+//
+// Propagation is used only by the next Transactor.BeginCtx (or Begin) call with this context.
+// Context returned by BeginCtx does not contain propagation anymore,
+// so nested BeginCtx calls use default propagation of their transactors
+func SetPropagation(ctx context.Context, propagation Propagation) context.Context {
+	return context.WithValue(ctx, propagationKey, propagation)
+}
+
+// GetPropagation returns propagation from context or PropagationDefault if it is not set
+func GetPropagation(ctx context.Context) Propagation {
+	propagation, ok := ctx.Value(propagationKey).(Propagation)
+	if !ok {
+		return PropagationDefault
+	}
+
+	return propagation
+}
+
+// ResetPropagation returns context without propagation.
+//
+// Used by Transactor implementations to "consume" propagation set by SetPropagation
+func ResetPropagation(ctx context.Context) context.Context {
+	if GetPropagation(ctx) == PropagationDefault {
+		return ctx
+	}
+
+	return SetPropagation(ctx, PropagationDefault)
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+type breakerClient struct {
+	ShardClient
+	breaker *storage.CircuitBreaker
+}
+
+// Breaker returns circuit breaker of shard client
+func (client *breakerClient) Breaker() *storage.CircuitBreaker {
+	return client.breaker
+}
+
+// WithCircuitBreaker guards every shard client of Clients by its own circuit breaker and returns the same Clients.
+//
+// Commands of shard client fail fast with storage.ErrCircuitOpen if breaker of selected shard is open.
+// Redis clients returned by Client, Pipeline & TxPipeline are not guarded. If settings do not contain IsFailure,
+// only connection errors are counted as failures (error replies of redis mean shard is available).
+// Shards added to Clients later are guarded too
+func WithCircuitBreaker(clients *Clients, settings storage.BreakerSettings) *Clients {
+	if settings.IsFailure == nil {
+		settings.IsFailure = IsConnectionFailure
+	}
+
+	clients.guardWith(func(client ShardClient) ShardClient {
+		return &breakerClient{
+			ShardClient: client,
+			breaker:     storage.NewCircuitBreaker("redis/"+client.Key(), settings),
+		}
+	})
+	return clients
+}
+
+// AvailableClients returns clients which breaker is not open.
+//
+// Can be used by selectors which may choose another shard if selected one is unavailable
+func AvailableClients(clients []ShardClient) []ShardClient {
+	available := make([]ShardClient, 0, len(clients))
+	for _, client := range clients {
+		breaker, ok := storage.GetBreaker(client)
+		if ok && breaker.State() == storage.BreakerOpen {
+			continue
+		}
+
+		available = append(available, client)
+	}
+
+	return available
+}
+
+// IsConnectionFailure checks if error means redis is unavailable.
+//
+// Error replies of redis are not failures because redis responded
+func IsConnectionFailure(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var replyErr redis.Error
+	return !errors.As(err, &replyErr)
+}
+
+type breakerKey struct{}
+
+// withShardBreaker sets circuit breaker of shard client to context, so commands run with the context are guarded by it
+func withShardBreaker(ctx context.Context, client ShardClient) context.Context {
+	breaker, ok := storage.GetBreaker(client)
+	if !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, breakerKey{}, breaker)
+}
+
+// takeBreaker returns breaker from context and context without it
+func takeBreaker(ctx context.Context) (context.Context, *storage.CircuitBreaker) {
+	breaker, _ := ctx.Value(breakerKey{}).(*storage.CircuitBreaker)
+	if breaker == nil {
+		return ctx, nil
+	}
+
+	return context.WithValue(ctx, breakerKey{}, (*storage.CircuitBreaker)(nil)), breaker
+}
+
+// breakerHook rejects commands if breaker from context is open and reports command results to it.
+//
+// Commands without breaker in context are not guarded. Hook removes breaker from context of the next hooks,
+// so client added to several Clients reports every command once
+type breakerHook struct{}
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, breaker := takeBreaker(ctx)
+		if err := breaker.Allow(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+
+		err := next(ctx, cmd)
+		breaker.Done(err)
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, breaker := takeBreaker(ctx)
+		if err := breaker.Allow(); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+
+		err := next(ctx, cmds)
+		breaker.Done(err)
+		return err
+	}
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIsConnectionFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "nil reply", err: redis.Nil, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "error reply", err: errors.New("ERR wrong number of arguments"), want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConnectionFailure(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	selector := LookupSelector(map[string]string{
+		"a": "a",
+		"b": "b",
+	})
+
+	clients := newClients([]ShardClient{newTestShard(t, "a")}, selector)
+	guarded := WithCircuitBreaker(clients, storage.BreakerSettings{FailureThreshold: 1})
+	if guarded != clients {
+		t.Fatal("expected clients to be guarded in place")
+	}
+
+	if err := clients.Add(newTestShard(t, "b")); err != nil {
+		t.Fatalf("add shard: %v", err)
+	}
+
+	// the same redis client in two Clients has two breaker hooks, but command is reported to breaker once
+	shared := newTestShard(t, "a")
+	_ = newClients([]ShardClient{shared}, selector)
+	twice := WithCircuitBreaker(newClients([]ShardClient{shared}, selector), storage.BreakerSettings{FailureThreshold: 2})
+
+	tests := []struct {
+		name     string
+		clients  *Clients
+		shardKey string
+		// wantOpen is expected error of the second command after the first one failed to connect
+		wantOpen bool
+	}{
+		{name: "guarded shard", clients: clients, shardKey: "a", wantOpen: true},
+		{name: "shard added at runtime", clients: clients, shardKey: "b", wantOpen: true},
+		{name: "not guarded clients", clients: newClients([]ShardClient{newTestShard(t, "a")}, selector), shardKey: "a"},
+		{name: "client hooked twice", clients: twice, shardKey: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := storage.WithShardKey(context.Background(), tt.shardKey)
+			client := NewShard(tt.clients)
+
+			err := client.Set(ctx, "user:1", "a")
+			if err == nil || errors.Is(err, storage.ErrCircuitOpen) {
+				t.Fatalf("expected connection error, got %v", err)
+			}
+
+			_, err = client.Get(ctx, "user:1")
+			if errors.Is(err, storage.ErrCircuitOpen) != tt.wantOpen {
+				t.Fatalf("expected open circuit %v, got %v", tt.wantOpen, err)
+			}
+		})
+	}
+}
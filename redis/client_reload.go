@@ -0,0 +1,285 @@
+package redis
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync/atomic"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// Add adds shard client. Shard key must be unique
+func (c *Clients) Add(client ShardClient) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	snapshot := c.load()
+	if _, ok := snapshot.drainers[client.Key()]; ok {
+		return storage.ErrShardExists.
+			AddParam("key", client.Key())
+	}
+
+	next := snapshot.clone()
+	next.add(c.prepare(client))
+	c.snapshot.Store(next)
+	return nil
+}
+
+// Remove removes shard client by key.
+//
+// New commands do not select removed shard. Commands sent to removed shard after selection
+// fail with storage.ErrShardRemoved. Client is closed after in-flight commands are finished
+// or context is done (then storage.ErrDrainTimeout is returned, but client is closed anyway)
+func (c *Clients) Remove(ctx context.Context, key string) error {
+	c.mx.Lock()
+	snapshot := c.load()
+	idx := snapshot.index(key)
+	if idx == -1 {
+		c.mx.Unlock()
+		return storage.ErrShardNotFound.
+			AddParam("key", key)
+	}
+
+	next := snapshot.clone()
+	next.remove(key)
+	c.snapshot.Store(next)
+	c.mx.Unlock()
+
+	return drainShard(ctx, snapshot.clients[idx], snapshot.drainers[key])
+}
+
+// Replace replaces shard client with the same key, for example to rotate host of shard.
+//
+// New commands use new client at once. Old client is closed after its in-flight commands are finished
+func (c *Clients) Replace(ctx context.Context, client ShardClient) error {
+	c.mx.Lock()
+	snapshot := c.load()
+	idx := snapshot.index(client.Key())
+	if idx == -1 {
+		c.mx.Unlock()
+		return storage.ErrShardNotFound.
+			AddParam("key", client.Key())
+	}
+
+	prepared, drainer := c.prepare(client)
+	next := snapshot.clone()
+	next.replace(idx, prepared, drainer)
+	c.snapshot.Store(next)
+	c.mx.Unlock()
+
+	return drainShard(ctx, snapshot.clients[idx], snapshot.drainers[client.Key()])
+}
+
+// Reload applies new shards config: connects new shards, reconnects shards which config changed
+// and removes shards which are missing in config. Not changed shards keep their clients.
+//
+// New shards are connected without blocking other changes of shards. If shards are changed concurrently,
+// clients are closed and Reload starts again with new shards list.
+// If one of shards can not be connected, no changes are applied.
+// Available only for Clients created by ConnectShards
+func (c *Clients) Reload(ctx context.Context, configs []ShardConnectConfig) error {
+	if c.connect == nil {
+		return storage.ErrReloadNotSupported
+	}
+
+	if err := validateConnectConfigs(configs); err != nil {
+		return err
+	}
+
+	for {
+		snapshot := c.load()
+		connected, err := c.connectChanged(snapshot, configs)
+		if err != nil {
+			return err
+		}
+
+		c.mx.Lock()
+		if c.load() != snapshot {
+			c.mx.Unlock()
+			closeClients(connected)
+			continue
+		}
+
+		next := c.reloaded(snapshot, configs, connected)
+		c.snapshot.Store(next)
+		c.mx.Unlock()
+
+		// close removed & replaced shards
+		wg := errgroup.Group{}
+		for _, client := range snapshot.clients {
+			key := client.Key()
+			if next.drainers[key] == snapshot.drainers[key] {
+				continue
+			}
+
+			wg.Go(func() error {
+				return drainShard(ctx, client, snapshot.drainers[key])
+			})
+		}
+
+		return wg.Wait()
+	}
+}
+
+// connectChanged connects shards which are missing in snapshot or which config is changed.
+// If one of shards can not be connected, already connected ones are closed
+func (c *Clients) connectChanged(snapshot *clientsSnapshot, configs []ShardConnectConfig) (map[string]ShardClient, error) {
+	connected := make(map[string]ShardClient)
+	for _, config := range configs {
+		current, ok := snapshot.configs[config.Key]
+		if ok && snapshot.index(config.Key) != -1 && equalConnectConfigs(current, config) {
+			continue
+		}
+
+		client, err := c.connect(config)
+		if err != nil {
+			closeClients(connected)
+			return nil, err
+		}
+
+		connected[config.Key] = client
+	}
+
+	return connected, nil
+}
+
+// reloaded builds new shards list in order of config from snapshot & connected shards
+func (c *Clients) reloaded(
+	snapshot *clientsSnapshot,
+	configs []ShardConnectConfig,
+	connected map[string]ShardClient,
+) *clientsSnapshot {
+	next := &clientsSnapshot{
+		clients:  make([]ShardClient, 0, len(configs)),
+		drainers: make(map[string]*storage.Drainer, len(configs)),
+		configs:  make(map[string]ShardConnectConfig, len(configs)),
+	}
+	for _, config := range configs {
+		next.configs[config.Key] = config
+		if client, ok := connected[config.Key]; ok {
+			next.add(c.prepare(client))
+			continue
+		}
+
+		next.clients = append(next.clients, snapshot.clients[snapshot.index(config.Key)])
+		next.drainers[config.Key] = snapshot.drainers[config.Key]
+	}
+
+	return next
+}
+
+// closeClients closes shard clients which were connected but not applied
+func closeClients(clients map[string]ShardClient) {
+	for _, client := range clients {
+		_ = client.Close()
+	}
+}
+
+// prepare adds hooks to client added at runtime & wraps it by guard of Clients if it exist.
+// Returns client with its new drainer
+func (c *Clients) prepare(client ShardClient) (ShardClient, *storage.Drainer) {
+	drainer := c.hooks.drain(client)
+
+	if c.guard == nil {
+		return client, drainer
+	}
+
+	return c.guard(client), drainer
+}
+
+func (s *clientsSnapshot) clone() *clientsSnapshot {
+	return &clientsSnapshot{
+		clients:  slices.Clone(s.clients),
+		drainers: maps.Clone(s.drainers),
+		configs:  maps.Clone(s.configs),
+	}
+}
+
+func (s *clientsSnapshot) index(key string) int {
+	return slices.IndexFunc(s.clients, func(client ShardClient) bool {
+		return client.Key() == key
+	})
+}
+
+func (s *clientsSnapshot) add(client ShardClient, drainer *storage.Drainer) {
+	s.clients = append(s.clients, client)
+	s.drainers[client.Key()] = drainer
+}
+
+func (s *clientsSnapshot) remove(key string) {
+	s.clients = slices.DeleteFunc(s.clients, func(client ShardClient) bool {
+		return client.Key() == key
+	})
+	delete(s.drainers, key)
+	delete(s.configs, key)
+}
+
+// replace sets client to the same position, so selectors depending on shards order keep routing
+func (s *clientsSnapshot) replace(idx int, client ShardClient, drainer *storage.Drainer) {
+	s.clients[idx] = client
+	s.drainers[client.Key()] = drainer
+	delete(s.configs, client.Key())
+}
+
+// drainShard waits for in-flight commands of removed shard and closes it
+func drainShard(ctx context.Context, client ShardClient, drainer *storage.Drainer) error {
+	drainErr := drainer.Drain(ctx)
+	if err := client.Close(); err != nil {
+		return ErrCloseShard.
+			SetError(err).
+			AddParam("key", client.Key())
+	}
+
+	return drainErr
+}
+
+func equalConnectConfigs(a, b ShardConnectConfig) bool {
+	return a.Key == b.Key &&
+		a.Address == b.Address &&
+		a.Port == b.Port &&
+		a.DB == b.DB &&
+		a.Password == b.Password &&
+		slices.Equal(a.Conditions, b.Conditions)
+}
+
+// drainHook registers every command & pipeline as in-flight and rejects them if shard is removed.
+//
+// Drainer is read on every call, because it is changed if client is added to shards again
+type drainHook struct {
+	drainer *atomic.Pointer[storage.Drainer]
+}
+
+func (h drainHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h drainHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		drainer := h.drainer.Load()
+		if !drainer.Acquire() {
+			cmd.SetErr(storage.ErrShardRemoved)
+			return storage.ErrShardRemoved
+		}
+		defer drainer.Release()
+
+		return next(ctx, cmd)
+	}
+}
+
+func (h drainHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		drainer := h.drainer.Load()
+		if !drainer.Acquire() {
+			for _, cmd := range cmds {
+				cmd.SetErr(storage.ErrShardRemoved)
+			}
+			return storage.ErrShardRemoved
+		}
+		defer drainer.Release()
+
+		return next(ctx, cmds)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/boostgo/contextx"
@@ -24,11 +26,76 @@ type shardClient struct {
 //
 // Need to provide Clients object which contains multiple clients for sharding
 func NewShard(clients *Clients) Client {
+	clients.trace()
+
 	return &shardClient{
 		clients: clients,
 	}
 }
 
+// cmdable returns transaction pipeline from context if it exist or client of selected shard.
+//
+// Transaction pipeline is bound to the shard selected when transaction began.
+// Inside transaction commands are queued and their results are available only after transaction commit.
+// Returned context must be used for command, so command is guarded by circuit breaker of selected shard
+func (c *shardClient) cmdable(ctx context.Context) (context.Context, redis.Cmdable, error) {
+	if scope, ok := getScope(ctx, c.txOwner()); ok && scope.tx != nil {
+		if err := c.checkTxShard(ctx, scope); err != nil {
+			return ctx, nil, err
+		}
+
+		return ctx, scope.tx, nil
+	}
+
+	raw, err := c.clients.Get(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return withShardBreaker(ctx, raw), raw.Client(), nil
+}
+
+// checkTxShard checks if selector chooses shard where transaction pipeline is bound.
+// Transaction set by SetTx is not checked, because its shard is unknown
+func (c *shardClient) checkTxShard(ctx context.Context, scope *txScope) error {
+	if scope.shard == "" {
+		return nil
+	}
+
+	raw, err := c.clients.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if raw.Key() != scope.shard {
+		return ErrTxShardMismatch.SetParams([]errorx.Parameter{
+			{Key: "tx_shard", Value: scope.shard},
+			{Key: "shard", Value: raw.Key()},
+		})
+	}
+
+	return nil
+}
+
+// reader returns client of selected shard for read command or command which reply is returned to the caller
+// (SetNX, HIncrBy, Eval, ...). Such commands can not be queued in transaction, because their replies are available only after commit
+func (c *shardClient) reader(ctx context.Context) (context.Context, redis.Cmdable, error) {
+	if _, ok := getTx(ctx, c.txOwner()); ok {
+		return ctx, nil, ErrTransactionRead
+	}
+
+	raw, err := c.clients.Get(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return withShardBreaker(ctx, raw), raw.Client(), nil
+}
+
+func (c *shardClient) txOwner() any {
+	return c.clients
+}
+
 func (c *shardClient) Close() error {
 	return c.clients.Close()
 }
@@ -72,17 +139,31 @@ func (c *shardClient) TxPipeline(ctx context.Context) (redis.Pipeliner, error) {
 	return raw.Client().TxPipeline(), nil
 }
 
+// beginShardTx creates transaction pipeline of selected shard and returns key of the shard
+func (c *shardClient) beginShardTx(ctx context.Context) (redis.Pipeliner, string, error) {
+	if err := contextx.Validate(ctx); err != nil {
+		return nil, "", err
+	}
+
+	raw, err := c.clients.Get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return raw.Client().TxPipeline(), raw.Key(), nil
+}
+
 func (c *shardClient) Keys(ctx context.Context, pattern string) ([]string, error) {
 	if err := contextx.Validate(ctx); err != nil {
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().Keys(ctx, pattern).Result()
+	return raw.Keys(ctx, pattern).Result()
 }
 
 func (c *shardClient) Delete(ctx context.Context, keys ...string) error {
@@ -94,7 +175,7 @@ func (c *shardClient) Delete(ctx context.Context, keys ...string) error {
 		return nil
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
@@ -108,7 +189,7 @@ func (c *shardClient) Delete(ctx context.Context, keys ...string) error {
 		return nil
 	}
 
-	return raw.Client().Del(ctx, keys...).Err()
+	return raw.Del(ctx, keys...).Err()
 }
 
 func (c *shardClient) Dump(ctx context.Context, key string) (string, error) {
@@ -116,12 +197,12 @@ func (c *shardClient) Dump(ctx context.Context, key string) (string, error) {
 		return "", err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return raw.Client().Dump(ctx, key).Result()
+	return raw.Dump(ctx, key).Result()
 }
 
 func (c *shardClient) Rename(ctx context.Context, oldKey, newKey string) error {
@@ -137,12 +218,12 @@ func (c *shardClient) Rename(ctx context.Context, oldKey, newKey string) error {
 			AddParam("key_type", "new")
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().Rename(ctx, oldKey, newKey).Err()
+	return raw.Rename(ctx, oldKey, newKey).Err()
 }
 
 func (c *shardClient) Refresh(ctx context.Context, key string, ttl time.Duration) error {
@@ -150,12 +231,12 @@ func (c *shardClient) Refresh(ctx context.Context, key string, ttl time.Duration
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().Expire(ctx, key, ttl).Err()
+	return raw.Expire(ctx, key, ttl).Err()
 }
 
 func (c *shardClient) RefreshAt(ctx context.Context, key string, at time.Time) error {
@@ -163,12 +244,12 @@ func (c *shardClient) RefreshAt(ctx context.Context, key string, at time.Time) e
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().ExpireAt(ctx, key, at).Err()
+	return raw.ExpireAt(ctx, key, at).Err()
 }
 
 func (c *shardClient) TTL(ctx context.Context, key string) (time.Duration, error) {
@@ -176,12 +257,12 @@ func (c *shardClient) TTL(ctx context.Context, key string) (time.Duration, error
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	ttl, err := raw.Client().TTL(ctx, key).Result()
+	ttl, err := raw.TTL(ctx, key).Result()
 	if err != nil {
 		return ttl, err
 	}
@@ -204,12 +285,12 @@ func (c *shardClient) Set(ctx context.Context, key string, value any, ttl ...tim
 		expireAt = ttl[0]
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().Set(ctx, key, value, expireAt).Err()
+	return raw.Set(ctx, key, value, expireAt).Err()
 }
 
 func (c *shardClient) SetNX(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
@@ -217,12 +298,12 @@ func (c *shardClient) SetNX(ctx context.Context, key string, value any, ttl time
 		return false, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	return raw.Client().SetNX(ctx, key, value, ttl).Result()
+	return raw.SetNX(ctx, key, value, ttl).Result()
 }
 
 func (c *shardClient) Get(ctx context.Context, key string) (string, error) {
@@ -230,12 +311,12 @@ func (c *shardClient) Get(ctx context.Context, key string) (string, error) {
 		return "", err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	result, err := raw.Client().Get(ctx, key).Result()
+	result, err := raw.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return result, ErrKeyNotFound.
@@ -253,12 +334,12 @@ func (c *shardClient) MGet(ctx context.Context, keys []string) ([]any, error) {
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := raw.Client().MGet(ctx, keys...).Result()
+	result, err := raw.MGet(ctx, keys...).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -271,12 +352,12 @@ func (c *shardClient) Exist(ctx context.Context, key string) (int64, error) {
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	return raw.Client().Exists(ctx, key).Result()
+	return raw.Exists(ctx, key).Result()
 }
 
 func (c *shardClient) GetBytes(ctx context.Context, key string) ([]byte, error) {
@@ -284,12 +365,12 @@ func (c *shardClient) GetBytes(ctx context.Context, key string) ([]byte, error)
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := raw.Client().Get(ctx, key).Bytes()
+	result, err := raw.Get(ctx, key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, ErrKeyNotFound.
@@ -307,12 +388,12 @@ func (c *shardClient) GetInt(ctx context.Context, key string) (int, error) {
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	result, err := raw.Client().Get(ctx, key).Int()
+	result, err := raw.Get(ctx, key).Int()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return 0, ErrKeyNotFound.
@@ -330,13 +411,13 @@ func (c *shardClient) Parse(ctx context.Context, key string, export any) error {
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return err
 	}
 
 	var result []byte
-	result, err = raw.Client().Get(ctx, key).Bytes()
+	result, err = raw.Get(ctx, key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return ErrKeyNotFound.
@@ -354,12 +435,12 @@ func (c *shardClient) HSet(ctx context.Context, key string, value map[string]any
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().HSet(ctx, key, value).Err()
+	return raw.HSet(ctx, key, value).Err()
 }
 
 func (c *shardClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
@@ -367,12 +448,12 @@ func (c *shardClient) HGetAll(ctx context.Context, key string) (map[string]strin
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HGetAll(ctx, key).Result()
+	return raw.HGetAll(ctx, key).Result()
 }
 
 func (c *shardClient) HGet(ctx context.Context, key, field string) (string, error) {
@@ -380,12 +461,12 @@ func (c *shardClient) HGet(ctx context.Context, key, field string) (string, erro
 		return "", err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return raw.Client().HGet(ctx, key, field).Result()
+	return raw.HGet(ctx, key, field).Result()
 }
 
 func (c *shardClient) HGetInt(ctx context.Context, key, field string) (int, error) {
@@ -393,12 +474,12 @@ func (c *shardClient) HGetInt(ctx context.Context, key, field string) (int, erro
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	return raw.Client().HGet(ctx, key, field).Int()
+	return raw.HGet(ctx, key, field).Int()
 }
 
 func (c *shardClient) HGetBool(ctx context.Context, key, field string) (bool, error) {
@@ -406,12 +487,12 @@ func (c *shardClient) HGetBool(ctx context.Context, key, field string) (bool, er
 		return false, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	return raw.Client().HGet(ctx, key, field).Bool()
+	return raw.HGet(ctx, key, field).Bool()
 }
 
 func (c *shardClient) HExist(ctx context.Context, key, field string) (bool, error) {
@@ -419,12 +500,12 @@ func (c *shardClient) HExist(ctx context.Context, key, field string) (bool, erro
 		return false, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	return raw.Client().HExists(ctx, key, field).Result()
+	return raw.HExists(ctx, key, field).Result()
 }
 
 func (c *shardClient) HDelete(ctx context.Context, key string, fields ...string) error {
@@ -432,12 +513,12 @@ func (c *shardClient) HDelete(ctx context.Context, key string, fields ...string)
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().HDel(ctx, key, fields...).Err()
+	return raw.HDel(ctx, key, fields...).Err()
 }
 
 func (c *shardClient) HScan(
@@ -451,12 +532,12 @@ func (c *shardClient) HScan(
 		return nil, 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return raw.Client().HScan(ctx, key, cursor, pattern, count).Result()
+	return raw.HScan(ctx, key, cursor, pattern, count).Result()
 }
 
 func (c *shardClient) Scan(
@@ -469,12 +550,12 @@ func (c *shardClient) Scan(
 		return nil, 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return raw.Client().Scan(ctx, cursor, pattern, count).Result()
+	return raw.Scan(ctx, cursor, pattern, count).Result()
 }
 
 func (c *shardClient) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
@@ -482,12 +563,12 @@ func (c *shardClient) HIncrBy(ctx context.Context, key, field string, incr int64
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	return raw.Client().HIncrBy(ctx, key, field, incr).Result()
+	return raw.HIncrBy(ctx, key, field, incr).Result()
 }
 
 func (c *shardClient) HIncrByFloat(ctx context.Context, key, field string, incr float64) (float64, error) {
@@ -495,12 +576,12 @@ func (c *shardClient) HIncrByFloat(ctx context.Context, key, field string, incr
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	return raw.Client().HIncrByFloat(ctx, key, field, incr).Result()
+	return raw.HIncrByFloat(ctx, key, field, incr).Result()
 }
 
 func (c *shardClient) HKeys(ctx context.Context, key string) ([]string, error) {
@@ -508,12 +589,12 @@ func (c *shardClient) HKeys(ctx context.Context, key string) ([]string, error) {
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HKeys(ctx, key).Result()
+	return raw.HKeys(ctx, key).Result()
 }
 
 func (c *shardClient) HLen(ctx context.Context, key string) (int64, error) {
@@ -521,12 +602,12 @@ func (c *shardClient) HLen(ctx context.Context, key string) (int64, error) {
 		return 0, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	return raw.Client().HLen(ctx, key).Result()
+	return raw.HLen(ctx, key).Result()
 }
 
 func (c *shardClient) HMGet(ctx context.Context, key string, fields ...string) ([]any, error) {
@@ -538,12 +619,12 @@ func (c *shardClient) HMGet(ctx context.Context, key string, fields ...string) (
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HMGet(ctx, key, fields...).Result()
+	return raw.HMGet(ctx, key, fields...).Result()
 }
 
 func (c *shardClient) HMSet(ctx context.Context, key string, values ...any) error {
@@ -555,12 +636,12 @@ func (c *shardClient) HMSet(ctx context.Context, key string, values ...any) erro
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().HMSet(ctx, key, values...).Err()
+	return raw.HMSet(ctx, key, values...).Err()
 }
 
 func (c *shardClient) HSetNX(ctx context.Context, key, field string, value any) error {
@@ -568,12 +649,12 @@ func (c *shardClient) HSetNX(ctx context.Context, key, field string, value any)
 		return err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.cmdable(ctx)
 	if err != nil {
 		return err
 	}
 
-	return raw.Client().HSetNX(ctx, key, field, value).Err()
+	return raw.HSetNX(ctx, key, field, value).Err()
 }
 
 func (c *shardClient) HScanNoValues(
@@ -587,12 +668,12 @@ func (c *shardClient) HScanNoValues(
 		return nil, cursor, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return raw.Client().HScanNoValues(ctx, key, cursor, pattern, count).Result()
+	return raw.HScanNoValues(ctx, key, cursor, pattern, count).Result()
 }
 
 func (c *shardClient) HVals(ctx context.Context, key string) ([]string, error) {
@@ -600,12 +681,12 @@ func (c *shardClient) HVals(ctx context.Context, key string) ([]string, error) {
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HVals(ctx, key).Result()
+	return raw.HVals(ctx, key).Result()
 }
 
 func (c *shardClient) HRandField(ctx context.Context, key string, count int) ([]string, error) {
@@ -613,12 +694,12 @@ func (c *shardClient) HRandField(ctx context.Context, key string, count int) ([]
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HRandField(ctx, key, count).Result()
+	return raw.HRandField(ctx, key, count).Result()
 }
 
 func (c *shardClient) HRandFieldWithValues(ctx context.Context, key string, count int) ([]redis.KeyValue, error) {
@@ -626,12 +707,12 @@ func (c *shardClient) HRandFieldWithValues(ctx context.Context, key string, coun
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HRandFieldWithValues(ctx, key, count).Result()
+	return raw.HRandFieldWithValues(ctx, key, count).Result()
 }
 
 func (c *shardClient) HExpire(
@@ -648,12 +729,12 @@ func (c *shardClient) HExpire(
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HExpire(ctx, key, expiration, fields...).Result()
+	return raw.HExpire(ctx, key, expiration, fields...).Result()
 }
 
 func (c *shardClient) HTTL(ctx context.Context, key string, fields ...string) ([]int64, error) {
@@ -665,12 +746,12 @@ func (c *shardClient) HTTL(ctx context.Context, key string, fields ...string) ([
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().HTTL(ctx, key, fields...).Result()
+	return raw.HTTL(ctx, key, fields...).Result()
 }
 
 func (c *shardClient) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
@@ -682,12 +763,12 @@ func (c *shardClient) Eval(ctx context.Context, script string, keys []string, ar
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().Eval(ctx, script, keys, args...).Result()
+	return raw.Eval(ctx, script, keys, args...).Result()
 }
 
 func (c *shardClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...any) (any, error) {
@@ -699,12 +780,12 @@ func (c *shardClient) EvalSha(ctx context.Context, sha1 string, keys []string, a
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().EvalSha(ctx, sha1, keys, args...).Result()
+	return raw.EvalSha(ctx, sha1, keys, args...).Result()
 }
 
 func (c *shardClient) EvalRO(ctx context.Context, script string, keys []string, args ...any) (any, error) {
@@ -716,12 +797,12 @@ func (c *shardClient) EvalRO(ctx context.Context, script string, keys []string,
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().EvalRO(ctx, script, keys, args...).Result()
+	return raw.EvalRO(ctx, script, keys, args...).Result()
 }
 
 func (c *shardClient) EvalShaRO(ctx context.Context, sha1 string, keys []string, args ...any) (any, error) {
@@ -733,48 +814,48 @@ func (c *shardClient) EvalShaRO(ctx context.Context, sha1 string, keys []string,
 		return nil, err
 	}
 
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().EvalShaRO(ctx, sha1, keys, args...).Result()
+	return raw.EvalShaRO(ctx, sha1, keys, args...).Result()
 }
 
 func (c *shardClient) ScriptExists(ctx context.Context, hashes ...string) ([]bool, error) {
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.Client().ScriptExists(ctx, hashes...).Result()
+	return raw.ScriptExists(ctx, hashes...).Result()
 }
 
 func (c *shardClient) ScriptFlush(ctx context.Context) (string, error) {
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return raw.Client().ScriptFlush(ctx).Result()
+	return raw.ScriptFlush(ctx).Result()
 }
 
 func (c *shardClient) ScriptKill(ctx context.Context) (string, error) {
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return raw.Client().ScriptKill(ctx).Result()
+	return raw.ScriptKill(ctx).Result()
 }
 
 func (c *shardClient) ScriptLoad(ctx context.Context, script string) (string, error) {
-	raw, err := c.clients.Get(ctx)
+	ctx, raw, err := c.reader(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return raw.Client().ScriptLoad(ctx, script).Result()
+	return raw.ScriptLoad(ctx, script).Result()
 }
 
 type ShardClient interface {
@@ -784,39 +865,202 @@ type ShardClient interface {
 	Close() error
 }
 
-// Clients contain all clients for shard client and selector for choosing connection
+// Clients contain all clients for shard client and selector for choosing connection.
+//
+// Shards can be added, removed or replaced at runtime (see Add, Remove, Replace & Reload).
+// Every command selects shard from snapshot of shards, so selector always sees consistent list
 type Clients struct {
-	clients  []ShardClient
+	// mx serializes changes of shards
+	mx       sync.Mutex
+	snapshot atomic.Pointer[clientsSnapshot]
 	selector ClientSelector
+	hooks    *clientsHooks
+	// connect creates shard client from config. Set only if Clients created by ConnectShards
+	connect func(config ShardConnectConfig) (ShardClient, error)
+	// guard wraps every added shard client, for example by circuit breaker
+	guard func(client ShardClient) ShardClient
+}
+
+// clientsSnapshot is immutable list of shards. Every change of shards creates new snapshot
+type clientsSnapshot struct {
+	clients []ShardClient
+	// drainers count in-flight commands of every shard
+	drainers map[string]*storage.Drainer
+	// configs of shards created from ShardConnectConfig, used by Reload
+	configs map[string]ShardConnectConfig
+}
+
+// clientsHooks are hooks of Clients, so every hook is added to client once.
+// Clients added at runtime receive the same hooks
+type clientsHooks struct {
+	mx      sync.Mutex
+	tracing bool
+	metrics []*QueryMetrics
+	// clients are hooks added to every redis client, so client added again after removal does not get them twice
+	clients map[redis.UniversalClient]*clientHooks
+}
+
+// clientHooks are hooks added to redis client
+type clientHooks struct {
+	// drainer counts in-flight commands of the current shard of client, it is changed every time client is added
+	drainer atomic.Pointer[storage.Drainer]
+	traced  bool
+	metrics []*QueryMetrics
 }
 
 func newClients(clients []ShardClient, selector ClientSelector) *Clients {
-	return &Clients{
-		clients:  clients,
+	hooks := &clientsHooks{
+		clients: make(map[redis.UniversalClient]*clientHooks, len(clients)),
+	}
+
+	drainers := make(map[string]*storage.Drainer, len(clients))
+	for _, client := range clients {
+		drainers[client.Key()] = hooks.drain(client)
+	}
+
+	c := &Clients{
 		selector: selector,
+		hooks:    hooks,
 	}
+	c.snapshot.Store(&clientsSnapshot{
+		clients:  clients,
+		drainers: drainers,
+		configs:  make(map[string]ShardConnectConfig),
+	})
+	return c
+}
+
+func (c *Clients) load() *clientsSnapshot {
+	return c.snapshot.Load()
 }
 
 // Get returns shard connect by using selector
 func (c *Clients) Get(ctx context.Context) (ShardClient, error) {
 	// get shard by provided selector
-	conn := c.selector(ctx, c.clients)
+	ctx, selectionErr := storage.TrackSelection(ctx)
+	conn := c.selector(ctx, c.load().clients)
 	if conn == nil {
+		if err := selectionErr(); err != nil {
+			return nil, err
+		}
+
 		return nil, storage.ErrConnNotSelected
 	}
 
 	return conn, nil
 }
 
-// Clients return all shard clients
+// trace adds tracing hook to every client once, even if Clients object is used by multiple shard clients.
+// Clients added after that are traced too
+func (c *Clients) trace() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.hooks.mx.Lock()
+	defer c.hooks.mx.Unlock()
+
+	if c.hooks.tracing {
+		return
+	}
+
+	c.hooks.tracing = true
+	for _, client := range c.load().clients {
+		c.hooks.hook(client)
+	}
+}
+
+// instrument adds metrics hook to every client once. Clients added after that are instrumented too
+func (c *Clients) instrument(metrics *QueryMetrics) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.hooks.mx.Lock()
+	defer c.hooks.mx.Unlock()
+
+	if slices.Contains(c.hooks.metrics, metrics) {
+		return
+	}
+
+	c.hooks.metrics = append(c.hooks.metrics, metrics)
+	for _, client := range c.load().clients {
+		c.hooks.hook(client)
+	}
+}
+
+// drain adds hooks of Clients to client and returns new drainer of the client.
+// Drainer is changed every time client is added, hooks are added only once
+func (h *clientsHooks) drain(client ShardClient) *storage.Drainer {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	drainer := &storage.Drainer{}
+	h.hook(client).drainer.Store(drainer)
+	return drainer
+}
+
+// hook adds hooks of Clients which client does not have yet. Must be called with locked mx.
+//
+// Drain hook counts in-flight commands & breaker hook reports them to circuit breaker set by WithCircuitBreaker
+func (h *clientsHooks) hook(client ShardClient) *clientHooks {
+	raw := client.Client()
+	hooks, ok := h.clients[raw]
+	if !ok {
+		hooks = &clientHooks{}
+		raw.AddHook(drainHook{
+			drainer: &hooks.drainer,
+		})
+		raw.AddHook(breakerHook{})
+		h.clients[raw] = hooks
+	}
+
+	if h.tracing && !hooks.traced {
+		raw.AddHook(newTraceHook(client.Key()))
+		hooks.traced = true
+	}
+
+	for _, metrics := range h.metrics {
+		if slices.Contains(hooks.metrics, metrics) {
+			continue
+		}
+
+		raw.AddHook(metrics.Hook(client.Key()))
+		hooks.metrics = append(hooks.metrics, metrics)
+	}
+
+	return hooks
+}
+
+// guardWith wraps every shard client by guard. Shards added later are wrapped too
+func (c *Clients) guardWith(guard func(client ShardClient) ShardClient) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	next := c.load().clone()
+	for idx, client := range next.clients {
+		next.clients[idx] = guard(client)
+	}
+
+	if prev := c.guard; prev != nil {
+		c.guard = func(client ShardClient) ShardClient {
+			return guard(prev(client))
+		}
+	} else {
+		c.guard = guard
+	}
+
+	c.snapshot.Store(next)
+}
+
+// Clients return all shard clients. Returned slice must not be modified
 func (c *Clients) Clients() []ShardClient {
-	return c.clients
+	return c.load().clients
 }
 
 // RawConnections returns all clients as []*sqlx.DB
 func (c *Clients) RawConnections() []redis.UniversalClient {
-	clients := make([]redis.UniversalClient, len(c.clients))
-	for idx, client := range c.clients {
+	shards := c.Clients()
+	clients := make([]redis.UniversalClient, len(shards))
+	for idx, client := range shards {
 		clients[idx] = client.Client()
 	}
 	return clients
@@ -826,7 +1070,7 @@ func (c *Clients) RawConnections() []redis.UniversalClient {
 func (c *Clients) Close() error {
 	wg := errgroup.Group{}
 
-	for _, conn := range c.clients {
+	for _, conn := range c.Clients() {
 		wg.Go(conn.Close)
 	}
 
@@ -21,6 +21,7 @@ func New(address string, port, db int, password string, opts ...Option) (Client,
 	if err != nil {
 		return nil, err
 	}
+	conn.AddHook(newTraceHook(""))
 
 	return &singleClient{
 		client: conn,
@@ -36,12 +37,42 @@ func Must(address string, port, db int, password string, opts ...Option) Client
 	return client
 }
 
+// NewFromClient creates client from existing connection and adds tracing hook to it.
+//
+// Connection may be wrapped several times: every command is still traced once
 func NewFromClient(conn *redis.Client) Client {
+	conn.AddHook(newTraceHook(""))
+
 	return &singleClient{
 		client: conn,
 	}
 }
 
+// cmdable returns transaction pipeline of client from context if it exist or client.
+//
+// Inside transaction commands are queued and their results are available only after transaction commit
+func (c *singleClient) cmdable(ctx context.Context) redis.Cmdable {
+	if tx, ok := getTx(ctx, c.txOwner()); ok {
+		return tx
+	}
+
+	return c.client
+}
+
+// reader returns client for read command or command which reply is returned to the caller (SetNX, HIncrBy, Eval, ...).
+// Such commands can not be queued in transaction, because their replies are available only after commit
+func (c *singleClient) reader(ctx context.Context) (redis.Cmdable, error) {
+	if _, ok := getTx(ctx, c.txOwner()); ok {
+		return nil, ErrTransactionRead
+	}
+
+	return c.client, nil
+}
+
+func (c *singleClient) txOwner() any {
+	return c.client
+}
+
 func (c *singleClient) Close() error {
 	return c.client.Close()
 }
@@ -75,7 +106,12 @@ func (c *singleClient) Keys(ctx context.Context, pattern string) ([]string, erro
 		return nil, err
 	}
 
-	return c.client.Keys(ctx, pattern).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.Keys(ctx, pattern).Result()
 }
 
 func (c *singleClient) Delete(ctx context.Context, keys ...string) error {
@@ -96,7 +132,7 @@ func (c *singleClient) Delete(ctx context.Context, keys ...string) error {
 		return nil
 	}
 
-	return c.client.Del(ctx, keys...).Err()
+	return c.cmdable(ctx).Del(ctx, keys...).Err()
 }
 
 func (c *singleClient) Dump(ctx context.Context, key string) (string, error) {
@@ -104,7 +140,12 @@ func (c *singleClient) Dump(ctx context.Context, key string) (string, error) {
 		return "", err
 	}
 
-	return c.client.Dump(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return raw.Dump(ctx, key).Result()
 }
 
 func (c *singleClient) Rename(ctx context.Context, oldKey, newKey string) error {
@@ -120,7 +161,7 @@ func (c *singleClient) Rename(ctx context.Context, oldKey, newKey string) error
 			AddParam("key_type", "new")
 	}
 
-	return c.client.Rename(ctx, oldKey, newKey).Err()
+	return c.cmdable(ctx).Rename(ctx, oldKey, newKey).Err()
 }
 
 func (c *singleClient) Refresh(ctx context.Context, key string, ttl time.Duration) error {
@@ -128,7 +169,7 @@ func (c *singleClient) Refresh(ctx context.Context, key string, ttl time.Duratio
 		return err
 	}
 
-	return c.client.Expire(ctx, key, ttl).Err()
+	return c.cmdable(ctx).Expire(ctx, key, ttl).Err()
 }
 
 func (c *singleClient) RefreshAt(ctx context.Context, key string, at time.Time) error {
@@ -136,7 +177,7 @@ func (c *singleClient) RefreshAt(ctx context.Context, key string, at time.Time)
 		return err
 	}
 
-	return c.client.ExpireAt(ctx, key, at).Err()
+	return c.cmdable(ctx).ExpireAt(ctx, key, at).Err()
 }
 
 func (c *singleClient) TTL(ctx context.Context, key string) (time.Duration, error) {
@@ -144,7 +185,12 @@ func (c *singleClient) TTL(ctx context.Context, key string) (time.Duration, erro
 		return 0, err
 	}
 
-	ttl, err := c.client.TTL(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl, err := raw.TTL(ctx, key).Result()
 	if err != nil {
 		return ttl, err
 	}
@@ -167,7 +213,7 @@ func (c *singleClient) Set(ctx context.Context, key string, value any, ttl ...ti
 		expireAt = ttl[0]
 	}
 
-	return c.client.Set(ctx, key, value, expireAt).Err()
+	return c.cmdable(ctx).Set(ctx, key, value, expireAt).Err()
 }
 
 func (c *singleClient) SetNX(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
@@ -175,7 +221,12 @@ func (c *singleClient) SetNX(ctx context.Context, key string, value any, ttl tim
 		return false, err
 	}
 
-	return c.client.SetNX(ctx, key, value, ttl).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return raw.SetNX(ctx, key, value, ttl).Result()
 }
 
 func (c *singleClient) Get(ctx context.Context, key string) (string, error) {
@@ -183,7 +234,12 @@ func (c *singleClient) Get(ctx context.Context, key string) (string, error) {
 		return "", err
 	}
 
-	result, err := c.client.Get(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := raw.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return result, ErrKeyNotFound.
@@ -201,7 +257,12 @@ func (c *singleClient) MGet(ctx context.Context, keys []string) ([]any, error) {
 		return nil, err
 	}
 
-	return c.client.MGet(ctx, keys...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.MGet(ctx, keys...).Result()
 }
 
 func (c *singleClient) Exist(ctx context.Context, key string) (int64, error) {
@@ -209,7 +270,12 @@ func (c *singleClient) Exist(ctx context.Context, key string) (int64, error) {
 		return 0, err
 	}
 
-	return c.client.Exists(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw.Exists(ctx, key).Result()
 }
 
 func (c *singleClient) GetBytes(ctx context.Context, key string) ([]byte, error) {
@@ -217,7 +283,12 @@ func (c *singleClient) GetBytes(ctx context.Context, key string) ([]byte, error)
 		return nil, err
 	}
 
-	result, err := c.client.Get(ctx, key).Bytes()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := raw.Get(ctx, key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return result, ErrKeyNotFound.
@@ -235,7 +306,12 @@ func (c *singleClient) GetInt(ctx context.Context, key string) (int, error) {
 		return 0, err
 	}
 
-	result, err := c.client.Get(ctx, key).Int()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := raw.Get(ctx, key).Int()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return result, ErrKeyNotFound.
@@ -253,7 +329,12 @@ func (c *singleClient) Parse(ctx context.Context, key string, export any) error
 		return err
 	}
 
-	result, err := c.client.Get(ctx, key).Bytes()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := raw.Get(ctx, key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return ErrKeyNotFound.
@@ -271,7 +352,7 @@ func (c *singleClient) HSet(ctx context.Context, key string, value map[string]an
 		return err
 	}
 
-	return c.client.HSet(ctx, key, value).Err()
+	return c.cmdable(ctx).HSet(ctx, key, value).Err()
 }
 
 func (c *singleClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
@@ -279,7 +360,12 @@ func (c *singleClient) HGetAll(ctx context.Context, key string) (map[string]stri
 		return nil, err
 	}
 
-	return c.client.HGetAll(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HGetAll(ctx, key).Result()
 }
 
 func (c *singleClient) HGet(ctx context.Context, key, field string) (string, error) {
@@ -287,7 +373,12 @@ func (c *singleClient) HGet(ctx context.Context, key, field string) (string, err
 		return "", err
 	}
 
-	return c.client.HGet(ctx, key, field).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return raw.HGet(ctx, key, field).Result()
 }
 
 func (c *singleClient) HGetInt(ctx context.Context, key, field string) (int, error) {
@@ -295,7 +386,12 @@ func (c *singleClient) HGetInt(ctx context.Context, key, field string) (int, err
 		return 0, err
 	}
 
-	return c.client.HGet(ctx, key, field).Int()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw.HGet(ctx, key, field).Int()
 }
 
 func (c *singleClient) HGetBool(ctx context.Context, key, field string) (bool, error) {
@@ -303,7 +399,12 @@ func (c *singleClient) HGetBool(ctx context.Context, key, field string) (bool, e
 		return false, err
 	}
 
-	return c.client.HGet(ctx, key, field).Bool()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return raw.HGet(ctx, key, field).Bool()
 }
 
 func (c *singleClient) HExist(ctx context.Context, key, field string) (bool, error) {
@@ -311,7 +412,12 @@ func (c *singleClient) HExist(ctx context.Context, key, field string) (bool, err
 		return false, err
 	}
 
-	return c.client.HExists(ctx, key, field).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return raw.HExists(ctx, key, field).Result()
 }
 
 func (c *singleClient) HScan(
@@ -325,7 +431,12 @@ func (c *singleClient) HScan(
 		return nil, 0, err
 	}
 
-	return c.client.HScan(ctx, key, cursor, pattern, count).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return raw.HScan(ctx, key, cursor, pattern, count).Result()
 }
 
 func (c *singleClient) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
@@ -333,7 +444,12 @@ func (c *singleClient) HIncrBy(ctx context.Context, key, field string, incr int6
 		return 0, err
 	}
 
-	return c.client.HIncrBy(ctx, key, field, incr).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw.HIncrBy(ctx, key, field, incr).Result()
 }
 
 func (c *singleClient) HIncrByFloat(
@@ -345,7 +461,12 @@ func (c *singleClient) HIncrByFloat(
 		return 0, err
 	}
 
-	return c.client.HIncrByFloat(ctx, key, field, incr).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw.HIncrByFloat(ctx, key, field, incr).Result()
 }
 
 func (c *singleClient) HDelete(ctx context.Context, key string, fields ...string) error {
@@ -353,7 +474,7 @@ func (c *singleClient) HDelete(ctx context.Context, key string, fields ...string
 		return err
 	}
 
-	return c.client.HDel(ctx, key, fields...).Err()
+	return c.cmdable(ctx).HDel(ctx, key, fields...).Err()
 }
 
 func (c *singleClient) HKeys(ctx context.Context, key string) ([]string, error) {
@@ -361,7 +482,12 @@ func (c *singleClient) HKeys(ctx context.Context, key string) ([]string, error)
 		return nil, err
 	}
 
-	return c.client.HKeys(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HKeys(ctx, key).Result()
 }
 
 func (c *singleClient) HLen(ctx context.Context, key string) (int64, error) {
@@ -369,7 +495,12 @@ func (c *singleClient) HLen(ctx context.Context, key string) (int64, error) {
 		return 0, err
 	}
 
-	return c.client.HLen(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw.HLen(ctx, key).Result()
 }
 
 func (c *singleClient) HMGet(ctx context.Context, key string, fields ...string) ([]any, error) {
@@ -381,7 +512,12 @@ func (c *singleClient) HMGet(ctx context.Context, key string, fields ...string)
 		return []any{}, err
 	}
 
-	return c.client.HMGet(ctx, key, fields...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return []any{}, err
+	}
+
+	return raw.HMGet(ctx, key, fields...).Result()
 }
 
 func (c *singleClient) HMSet(ctx context.Context, key string, values ...any) error {
@@ -393,7 +529,7 @@ func (c *singleClient) HMSet(ctx context.Context, key string, values ...any) err
 		return err
 	}
 
-	return c.client.HMSet(ctx, key, values...).Err()
+	return c.cmdable(ctx).HMSet(ctx, key, values...).Err()
 }
 
 func (c *singleClient) HSetNX(ctx context.Context, key, field string, value any) error {
@@ -401,7 +537,7 @@ func (c *singleClient) HSetNX(ctx context.Context, key, field string, value any)
 		return err
 	}
 
-	return c.client.HSetNX(ctx, key, field, value).Err()
+	return c.cmdable(ctx).HSetNX(ctx, key, field, value).Err()
 }
 
 func (c *singleClient) Scan(
@@ -414,7 +550,12 @@ func (c *singleClient) Scan(
 		return nil, 0, err
 	}
 
-	return c.client.Scan(ctx, cursor, pattern, count).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return raw.Scan(ctx, cursor, pattern, count).Result()
 }
 
 func (c *singleClient) HScanNoValues(
@@ -428,7 +569,12 @@ func (c *singleClient) HScanNoValues(
 		return nil, 0, err
 	}
 
-	return c.client.HScanNoValues(ctx, key, cursor, pattern, count).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return raw.HScanNoValues(ctx, key, cursor, pattern, count).Result()
 }
 
 func (c *singleClient) HVals(ctx context.Context, key string) ([]string, error) {
@@ -436,7 +582,12 @@ func (c *singleClient) HVals(ctx context.Context, key string) ([]string, error)
 		return nil, err
 	}
 
-	return c.client.HVals(ctx, key).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HVals(ctx, key).Result()
 }
 
 func (c *singleClient) HRandField(ctx context.Context, key string, count int) ([]string, error) {
@@ -444,7 +595,12 @@ func (c *singleClient) HRandField(ctx context.Context, key string, count int) ([
 		return nil, err
 	}
 
-	return c.client.HRandField(ctx, key, count).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HRandField(ctx, key, count).Result()
 }
 
 func (c *singleClient) HRandFieldWithValues(
@@ -456,7 +612,12 @@ func (c *singleClient) HRandFieldWithValues(
 		return nil, err
 	}
 
-	return c.client.HRandFieldWithValues(ctx, key, count).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HRandFieldWithValues(ctx, key, count).Result()
 }
 
 func (c *singleClient) HExpire(
@@ -473,7 +634,12 @@ func (c *singleClient) HExpire(
 		return nil, err
 	}
 
-	return c.client.HExpire(ctx, key, expiration, fields...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HExpire(ctx, key, expiration, fields...).Result()
 }
 
 func (c *singleClient) HTTL(ctx context.Context, key string, fields ...string) ([]int64, error) {
@@ -485,7 +651,12 @@ func (c *singleClient) HTTL(ctx context.Context, key string, fields ...string) (
 		return nil, err
 	}
 
-	return c.client.HTTL(ctx, key, fields...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.HTTL(ctx, key, fields...).Result()
 }
 
 func (c *singleClient) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
@@ -493,7 +664,12 @@ func (c *singleClient) Eval(ctx context.Context, script string, keys []string, a
 		return nil, err
 	}
 
-	return c.client.Eval(ctx, script, keys, args...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.Eval(ctx, script, keys, args...).Result()
 }
 
 func (c *singleClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...any) (any, error) {
@@ -501,7 +677,12 @@ func (c *singleClient) EvalSha(ctx context.Context, sha1 string, keys []string,
 		return nil, err
 	}
 
-	return c.client.EvalSha(ctx, sha1, keys, args...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.EvalSha(ctx, sha1, keys, args...).Result()
 }
 
 func (c *singleClient) EvalRO(ctx context.Context, script string, keys []string, args ...any) (any, error) {
@@ -509,7 +690,12 @@ func (c *singleClient) EvalRO(ctx context.Context, script string, keys []string,
 		return nil, err
 	}
 
-	return c.client.EvalRO(ctx, script, keys, args...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.EvalRO(ctx, script, keys, args...).Result()
 }
 
 func (c *singleClient) EvalShaRO(ctx context.Context, sha1 string, keys []string, args ...any) (any, error) {
@@ -517,21 +703,46 @@ func (c *singleClient) EvalShaRO(ctx context.Context, sha1 string, keys []string
 		return nil, err
 	}
 
-	return c.client.EvalShaRO(ctx, sha1, keys, args...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.EvalShaRO(ctx, sha1, keys, args...).Result()
 }
 
 func (c *singleClient) ScriptExists(ctx context.Context, hashes ...string) ([]bool, error) {
-	return c.client.ScriptExists(ctx, hashes...).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.ScriptExists(ctx, hashes...).Result()
 }
 
 func (c *singleClient) ScriptFlush(ctx context.Context) (string, error) {
-	return c.client.ScriptFlush(ctx).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return raw.ScriptFlush(ctx).Result()
 }
 
 func (c *singleClient) ScriptKill(ctx context.Context) (string, error) {
-	return c.client.ScriptKill(ctx).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return raw.ScriptKill(ctx).Result()
 }
 
 func (c *singleClient) ScriptLoad(ctx context.Context, script string) (string, error) {
-	return c.client.ScriptLoad(ctx, script).Result()
+	raw, err := c.reader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return raw.ScriptLoad(ctx, script).Result()
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/boostgo/storage"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -90,6 +91,7 @@ func (conn *shardConnect) Client() redis.UniversalClient {
 	return conn.client
 }
 
+// Conditions returns routing conditions of client
 func (conn *shardConnect) Conditions() []string {
 	if conn.conditions == nil {
 		return []string{}
@@ -108,44 +110,71 @@ func ConnectShards(
 	selector ClientSelector,
 	options ...Option,
 ) (*Clients, error) {
-	// validate for connection key unique and for empty
-	// also, validate for empty connection string
+	if err := validateConnectConfigs(connectionStrings); err != nil {
+		return nil, err
+	}
+
+	connect := func(cs ShardConnectConfig) (ShardClient, error) {
+		connection, err := Connect(cs.Address, cs.Port, cs.DB, cs.Password, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		return newShardConnect(cs.Key, cs.Conditions, connection), nil
+	}
+
+	// connect every shard
+	connections := make([]ShardClient, len(connectionStrings))
+	for idx, cs := range connectionStrings {
+		connection, err := connect(cs)
+		if err != nil {
+			return nil, err
+		}
+
+		connections[idx] = connection
+	}
+
+	clients := newClients(connections, selector)
+	clients.connect = connect
+	for _, cs := range connectionStrings {
+		clients.load().configs[cs.Key] = cs
+	}
+
+	return clients, nil
+}
+
+// validateConnectConfigs validates for connection key unique and for empty.
+// Also, validates for empty address, port & shard conditions
+func validateConnectConfigs(connectionStrings []ShardConnectConfig) error {
 	keys := make(map[string]struct{}, len(connectionStrings))
 	for _, cs := range connectionStrings {
 		if cs.Key == "" {
-			return nil, ErrClientKeyEmpty
+			return ErrClientKeyEmpty
 		}
 
 		if cs.Address == "" {
-			return nil, ErrClientAddressEmpty.
+			return ErrClientAddressEmpty.
 				AddParam("key", cs.Key)
 		}
 
 		if cs.Port == 0 {
-			return nil, ErrClientPortZero.
+			return ErrClientPortZero.
 				AddParam("key", cs.Key)
 		}
 
 		if _, ok := keys[cs.Key]; ok {
-			return nil, ErrClientConnectionKeyDuplicate.
+			return ErrClientConnectionKeyDuplicate.
 				AddParam("key", cs.Key)
 		}
 
-		keys[cs.Key] = struct{}{}
-	}
-
-	// connect every shard
-	connections := make([]ShardClient, len(connectionStrings))
-	for idx, cs := range connectionStrings {
-		connection, err := Connect(cs.Address, cs.Port, cs.DB, cs.Password, options...)
-		if err != nil {
-			return nil, err
+		if err := storage.ValidateConditions(cs.Conditions); err != nil {
+			return err
 		}
 
-		connections[idx] = newShardConnect(cs.Key, cs.Conditions, connection)
+		keys[cs.Key] = struct{}{}
 	}
 
-	return newClients(connections, selector), nil
+	return nil
 }
 
 // MustConnectShards calls ConnectShards and if error catch throws panic
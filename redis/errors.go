@@ -11,7 +11,13 @@ var (
 	ErrClientPortZero               = errorx.New("redis.client_port_zero")
 	ErrClientConnectionKeyDuplicate = errorx.New("redis.client_connection_key_duplicate")
 	ErrClientKeyEmpty               = errorx.New("redis.client_key_empty")
+	ErrCloseShard                   = errorx.New("redis.close_shard")
 
 	ErrKeyNotFound = errorx.New("redis.key_not_found").SetError(errorx.ErrNotFound)
 	ErrInvalidKey  = errorx.New("redis.invalid_key")
+
+	ErrTransactorBegin  = errorx.New("redis.transactor.begin")
+	ErrTransactorCommit = errorx.New("redis.transactor.commit")
+	ErrTransactionRead  = errorx.New("redis.transaction_read")
+	ErrTxShardMismatch  = errorx.New("redis.tx_shard_mismatch")
 )
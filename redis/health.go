@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// ClientProbe creates health probe which pings provided client
+func ClientProbe(name string, client redis.UniversalClient) storage.Probe {
+	return storage.Probe{
+		Name: name,
+		Ping: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
+
+// Probes creates health probe for every shard client. Probes are named as "redis/<shard key>"
+//
+// Register it by storage.Health.RegisterSource to follow shards added or removed at runtime
+func (c *Clients) Probes() []storage.Probe {
+	clients := c.Clients()
+	probes := make([]storage.Probe, 0, len(clients))
+	for _, client := range clients {
+		probes = append(probes, ClientProbe("redis/"+client.Key(), client.Client()))
+	}
+
+	return probes
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+const metricsNamespace = "storage_redis"
+
+type statsCollector struct {
+	clients *Clients
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// NewStatsCollector creates prometheus collector of connection pool statistics (redis.PoolStats)
+// of every shard client. Metrics are labeled by shard key
+func NewStatsCollector(clients *Clients) prometheus.Collector {
+	labels := []string{"shard"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+	}
+
+	return &statsCollector{
+		clients: clients,
+
+		hits:       desc("pool_hits_total", "The number of times free connection was found in the pool."),
+		misses:     desc("pool_misses_total", "The number of times free connection was not found in the pool."),
+		timeouts:   desc("pool_timeouts_total", "The number of times a wait timeout occurred."),
+		totalConns: desc("pool_connections", "The number of total connections in the pool."),
+		idleConns:  desc("pool_idle_connections", "The number of idle connections in the pool."),
+		staleConns: desc("pool_stale_connections_total", "The number of stale connections removed from the pool."),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, client := range c.clients.Clients() {
+		key := client.Key()
+
+		pooler, ok := client.Client().(interface{ PoolStats() *redis.PoolStats })
+		if !ok {
+			continue
+		}
+		stats := pooler.PoolStats()
+
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), key)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), key)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts), key)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), key)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), key)
+		ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns), key)
+	}
+}
+
+// QueryMetrics is prometheus collector of command durations.
+//
+// Durations are observed by hooks added by Instrument or Hook
+// and labeled by command, shard key (empty for single client) and status ("ok" or "error")
+type QueryMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewQueryMetrics creates command durations collector. If buckets are not provided, prometheus.DefBuckets are used
+func NewQueryMetrics(buckets ...float64) *QueryMetrics {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	return &QueryMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "command_duration_seconds",
+			Help:      "Duration of commands by command name and shard.",
+			Buckets:   buckets,
+		}, []string{"command", "shard", "status"}),
+	}
+}
+
+func (m *QueryMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+}
+
+func (m *QueryMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+}
+
+// Instrument adds hook which observes command durations to every shard client.
+// Shard clients added later by Add, Replace or Reload are instrumented too
+func (m *QueryMetrics) Instrument(clients *Clients) {
+	clients.instrument(m)
+}
+
+// Hook returns go-redis hook which observes command durations of client with provided shard key
+func (m *QueryMetrics) Hook(shardKey string) redis.Hook {
+	return metricsHook{
+		metrics:  m,
+		shardKey: shardKey,
+	}
+}
+
+func (m *QueryMetrics) observe(command, shardKey string, start time.Time, err error) {
+	status := "ok"
+	if err != nil && !errors.Is(err, redis.Nil) {
+		status = "error"
+	}
+
+	m.duration.
+		WithLabelValues(command, shardKey, status).
+		Observe(time.Since(start).Seconds())
+}
+
+type metricsHook struct {
+	metrics  *QueryMetrics
+	shardKey string
+}
+
+func (h metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.metrics.observe(cmd.Name(), h.shardKey, start, err)
+		return err
+	}
+}
+
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		command := "pipeline"
+		if len(cmds) > 0 && cmds[0].Name() == "multi" {
+			command = "multi"
+		}
+
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.metrics.observe(command, h.shardKey, start, err)
+		return err
+	}
+}
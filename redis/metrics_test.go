@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boostgo/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestShard creates shard client which is never connected, so every command fails fast
+func newTestShard(t *testing.T, key string) ShardClient {
+	conn := redis.NewClient(&redis.Options{Addr: "localhost:0", MaxRetries: -1})
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return newShardConnect(key, nil, conn)
+}
+
+// observedCommands returns count of observed commands of shard with provided status
+func observedCommands(t *testing.T, metrics *QueryMetrics, command, shardKey, status string) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	observer := metrics.duration.WithLabelValues(command, shardKey, status)
+	if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("write metric: %v", err)
+	}
+
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestQueryMetricsInstrument(t *testing.T) {
+	tests := []struct {
+		name string
+		// change adds shard "b" after Instrument
+		change func(t *testing.T, clients *Clients)
+	}{
+		{
+			name: "initial shard",
+		},
+		{
+			name: "added shard",
+			change: func(t *testing.T, clients *Clients) {
+				if err := clients.Add(newTestShard(t, "b")); err != nil {
+					t.Fatalf("add shard: %v", err)
+				}
+			},
+		},
+		{
+			name: "replaced shard",
+			change: func(t *testing.T, clients *Clients) {
+				if err := clients.Add(newTestShard(t, "b")); err != nil {
+					t.Fatalf("add shard: %v", err)
+				}
+
+				if err := clients.Replace(context.Background(), newTestShard(t, "b")); err != nil {
+					t.Fatalf("replace shard: %v", err)
+				}
+			},
+		},
+		{
+			name: "re-added shard",
+			change: func(t *testing.T, clients *Clients) {
+				shard := newTestShard(t, "b")
+				if err := clients.Add(shard); err != nil {
+					t.Fatalf("add shard: %v", err)
+				}
+
+				if err := clients.Remove(context.Background(), "b"); err != nil {
+					t.Fatalf("remove shard: %v", err)
+				}
+
+				// the same redis client gets hooks once and is not rejected by drainer of removed shard
+				conn := shard.Client().(*redis.Client)
+				if err := clients.Add(newShardConnect("b", nil, conn)); err != nil {
+					t.Fatalf("add shard again: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := newClients([]ShardClient{newTestShard(t, "a")}, LookupSelector(map[string]string{
+				"a": "a",
+				"b": "b",
+			}))
+
+			metrics := NewQueryMetrics()
+			metrics.Instrument(clients)
+			// instrumenting twice does not observe commands twice
+			metrics.Instrument(clients)
+
+			shardKey := "a"
+			if tt.change != nil {
+				tt.change(t, clients)
+				shardKey = "b"
+			}
+
+			ctx := storage.WithShardKey(context.Background(), shardKey)
+			err := NewShard(clients).Set(ctx, "user:1", "a")
+			if err == nil || errors.Is(err, storage.ErrShardRemoved) {
+				t.Fatalf("expected connection error, got %v", err)
+			}
+
+			if got := observedCommands(t, metrics, "set", shardKey, "error"); got != 1 {
+				t.Fatalf("expected 1 observed command, got %d", got)
+			}
+		})
+	}
+}
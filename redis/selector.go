@@ -0,0 +1,40 @@
+package redis
+
+import "github.com/boostgo/storage"
+
+// HashSelector chooses shard by consistent hashing of shard key set by storage.WithShardKey.
+// See storage.HashSelector
+func HashSelector(virtualNodes int) ClientSelector {
+	return ClientSelector(storage.HashSelector[ShardClient](virtualNodes))
+}
+
+// RendezvousSelector chooses shard by rendezvous hashing of shard key set by storage.WithShardKey
+func RendezvousSelector() ClientSelector {
+	return ClientSelector(storage.RendezvousSelector[ShardClient]())
+}
+
+// ModuloSelector chooses shard by numeric shard key set by storage.WithShardKey modulo count of shards
+func ModuloSelector() ClientSelector {
+	return ClientSelector(storage.ModuloSelector[ShardClient]())
+}
+
+// NumericRangeSelector chooses shard which range contain numeric shard key set by storage.WithShardKey
+func NumericRangeSelector(ranges ...storage.NumericRange) ClientSelector {
+	return ClientSelector(storage.NumericRangeSelector[ShardClient](ranges...))
+}
+
+// LexicalRangeSelector chooses shard which range contain shard key set by storage.WithShardKey
+func LexicalRangeSelector(ranges ...storage.LexicalRange) ClientSelector {
+	return ClientSelector(storage.LexicalRangeSelector[ShardClient](ranges...))
+}
+
+// LookupSelector chooses shard by static map of shard keys set by storage.WithShardKey to client keys
+func LookupSelector(lookup map[string]string) ClientSelector {
+	return ClientSelector(storage.LookupSelector[ShardClient](lookup))
+}
+
+// ConditionSelector chooses the first shard which conditions match values set by storage.WithShardValue.
+// See storage.ConditionSelector
+func ConditionSelector(fallback string) ClientSelector {
+	return ClientSelector(storage.ConditionSelector[ShardClient](fallback))
+}
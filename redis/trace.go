@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const traceSystem = "redis"
+
+// tracedKey marks context of command or pipeline which span is already started by trace hook
+type tracedKey struct{}
+
+// markTraced returns context marked as traced and false if it was not marked before
+func markTraced(ctx context.Context) (context.Context, bool) {
+	if traced, _ := ctx.Value(tracedKey{}).(bool); traced {
+		return ctx, true
+	}
+
+	return context.WithValue(ctx, tracedKey{}, true), false
+}
+
+// traceHook starts span of every command and pipeline by tracer set by storage.SetTracer.
+//
+// Hook marks context of the next hooks as traced, so client which got several trace hooks
+// (for example, wrapped by NewFromClient twice) starts one span per command
+type traceHook struct {
+	shardKey string
+}
+
+func newTraceHook(shardKey string) redis.Hook {
+	return traceHook{
+		shardKey: shardKey,
+	}
+}
+
+func (h traceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h traceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, traced := markTraced(ctx)
+		if traced {
+			return next(ctx, cmd)
+		}
+
+		ctx, span := storage.StartQuerySpan(ctx, storage.QuerySpan{
+			System:    traceSystem,
+			Operation: cmd.Name(),
+			Statement: commandStatement(cmd),
+			ShardKey:  h.shardKey,
+		})
+		defer span.End()
+
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+		}
+
+		return err
+	}
+}
+
+func (h traceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, traced := markTraced(ctx)
+		if traced {
+			return next(ctx, cmds)
+		}
+
+		// transaction pipeline is wrapped by MULTI/EXEC commands
+		transaction := len(cmds) > 0 && cmds[0].Name() == "multi"
+		operation := "pipeline"
+		if transaction {
+			operation = "multi"
+		}
+
+		statements := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			statements = append(statements, commandStatement(cmd))
+		}
+
+		ctx, span := storage.StartQuerySpan(ctx, storage.QuerySpan{
+			System:      traceSystem,
+			Operation:   operation,
+			Statement:   strings.Join(statements, "\n"),
+			ShardKey:    h.shardKey,
+			Transaction: transaction,
+		})
+		defer span.End()
+
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+		}
+
+		return err
+	}
+}
+
+// commandStatement returns command name with key. Other arguments are not included to not expose values
+func commandStatement(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return cmd.Name()
+	}
+
+	key, ok := args[1].(string)
+	if !ok {
+		return cmd.Name()
+	}
+
+	return cmd.Name() + " " + key
+}
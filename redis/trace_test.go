@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCommandStatement(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		cmd  redis.Cmder
+		want string
+	}{
+		{name: "key", cmd: redis.NewStringCmd(ctx, "get", "user:1"), want: "get user:1"},
+		{name: "value is hidden", cmd: redis.NewStatusCmd(ctx, "set", "user:1", "secret"), want: "set user:1"},
+		{name: "no key", cmd: redis.NewStatusCmd(ctx, "ping"), want: "ping"},
+		{name: "not string key", cmd: redis.NewStatusCmd(ctx, "select", 1), want: "select"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandStatement(tt.cmd); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewFromClientTraced(t *testing.T) {
+	tests := []struct {
+		name string
+		// wraps is count of NewFromClient calls with the same client
+		wraps int
+	}{
+		{name: "wrapped once", wraps: 1},
+		{name: "wrapped twice", wraps: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := storage.NewRecorder()
+			storage.SetTracer(recorder)
+			t.Cleanup(func() {
+				storage.SetTracer(nil)
+			})
+
+			conn := redis.NewClient(&redis.Options{Addr: "localhost:0", MaxRetries: -1})
+			t.Cleanup(func() {
+				_ = conn.Close()
+			})
+
+			var client Client
+			for range tt.wraps {
+				client = NewFromClient(conn)
+			}
+
+			ctx := context.Background()
+			if err := client.Set(ctx, "user:1", "a", 0); err == nil {
+				t.Fatal("expected connection error")
+			}
+
+			_, _ = conn.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Get(ctx, "user:1")
+				return nil
+			})
+
+			// every command and pipeline gets one span however many times client is wrapped
+			spans := recorder.Spans()
+			if len(spans) != 2 {
+				t.Fatalf("expected 2 spans, got %d", len(spans))
+			}
+
+			if spans[0].Name != "redis.set" || spans[0].Attributes[storage.AttributeDBStatement] != "set user:1" {
+				t.Fatalf("unexpected span %+v", spans[0])
+			}
+
+			if len(spans[0].Errors) != 1 {
+				t.Fatalf("expected connection error to be recorded, got %v", spans[0].Errors)
+			}
+
+			if spans[1].Name != "redis.pipeline" {
+				t.Fatalf("unexpected span %+v", spans[1])
+			}
+		})
+	}
+}
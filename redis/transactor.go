@@ -0,0 +1,296 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"sync/atomic"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const transactionKey = "storage_redis_tx"
+
+// SetTx sets transaction pipeline of client to new context.
+//
+// Only provided client (and clients over the same connection) queue commands to the pipeline,
+// other clients keep running commands at once
+func SetTx(ctx context.Context, client Client, tx redis.Pipeliner) context.Context {
+	return setScope(ctx, txOwnerOf(client), &txScope{
+		tx:    tx,
+		owner: true,
+	})
+}
+
+// GetTx returns transaction pipeline of client from context if it exist
+func GetTx(ctx context.Context, client Client) (redis.Pipeliner, bool) {
+	return getTx(ctx, txOwnerOf(client))
+}
+
+func getTx(ctx context.Context, owner any) (redis.Pipeliner, bool) {
+	scope, ok := getScope(ctx, owner)
+	if !ok || scope.tx == nil {
+		return nil, false
+	}
+
+	return scope.tx, true
+}
+
+// txOwner is implemented by clients of the package.
+// Returns identity of connection, so clients over the same connection share transaction
+type txOwner interface {
+	txOwner() any
+}
+
+func txOwnerOf(client Client) any {
+	if owner, ok := client.(txOwner); ok {
+		return owner.txOwner()
+	}
+
+	return client
+}
+
+type redisTransactor struct {
+	client Client
+}
+
+// NewTransactor creates Redis transactor based on MULTI/EXEC.
+//
+// BeginCtx sets transaction pipeline of client to context and client methods called with this context
+// queue commands to pipeline instead of running them. Read commands and commands which reply is returned to the caller
+// (SetNX, HIncrBy, Eval, scripts, ...) fail with ErrTransactionRead, because their replies are available only after EXEC.
+// Transaction of shard client is bound to the shard selected by BeginCtx, commands routed to another shard
+// fail with ErrTxShardMismatch. CommitCtx runs all queued commands by EXEC.
+// Redis does not support nested transactions, so if context already contain transaction it is joined
+func NewTransactor(client Client) storage.Transactor {
+	return &redisTransactor{
+		client: client,
+	}
+}
+
+func (rt *redisTransactor) Key() string {
+	return transactionKey
+}
+
+func (rt *redisTransactor) IsTx(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	_, ok := GetTx(ctx, rt.client)
+	return ok
+}
+
+// Begin starts new transaction by propagation from context
+func (rt *redisTransactor) Begin(ctx context.Context) (storage.Transaction, error) {
+	txCtx, err := rt.BeginCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransaction(txCtx, rt), nil
+}
+
+// BeginCtx starts new transaction by propagation from context and sets it to returned context.
+//
+// CommitCtx & RollbackCtx must be called with returned context
+func (rt *redisTransactor) BeginCtx(ctx context.Context) (context.Context, error) {
+	propagation := storage.GetPropagation(ctx)
+	ctx = storage.ResetPropagation(ctx)
+
+	owner := txOwnerOf(rt.client)
+	parent, exist := getScope(ctx, owner)
+	switch propagation {
+	case storage.PropagationRequiresNew:
+	case storage.PropagationSupports:
+		if !exist {
+			return ctx, nil
+		}
+	case storage.PropagationMandatory:
+		if !exist {
+			return nil, storage.ErrTransactionRequired
+		}
+	case storage.PropagationNever:
+		if exist {
+			return nil, storage.ErrTransactionNotAllowed
+		}
+
+		return ctx, nil
+	}
+
+	if exist && propagation != storage.PropagationRequiresNew {
+		return setScope(ctx, owner, &txScope{
+			tx:    parent.tx,
+			shard: parent.shard,
+			state: parent.state,
+		}), nil
+	}
+
+	pipe, shard, err := rt.txPipeline(ctx)
+	if err != nil {
+		return nil, ErrTransactorBegin.SetError(err)
+	}
+
+	ctx, hooks := storage.BeginHooks(ctx)
+	return setScope(ctx, owner, &txScope{
+		tx:    pipe,
+		shard: shard,
+		owner: true,
+		state: &txState{},
+		hooks: hooks,
+	}), nil
+}
+
+// shardTxPipeliner is implemented by shard client which binds transaction pipeline to selected shard
+type shardTxPipeliner interface {
+	beginShardTx(ctx context.Context) (redis.Pipeliner, string, error)
+}
+
+// txPipeline creates transaction pipeline of client and returns key of shard if pipeline is bound to shard
+func (rt *redisTransactor) txPipeline(ctx context.Context) (redis.Pipeliner, string, error) {
+	if sharded, ok := rt.client.(shardTxPipeliner); ok {
+		return sharded.beginShardTx(ctx)
+	}
+
+	pipe, err := rt.client.TxPipeline(ctx)
+	return pipe, "", err
+}
+
+// CommitCtx runs commands queued in transaction by EXEC.
+//
+// Only transactor which began transaction runs EXEC. Joined transactions do nothing.
+// Redis applies queued commands even if some of them fail, so rollback hooks run only if redis discarded
+// the whole transaction (EXECABORT or failed WATCH). If commands are applied partially or result of EXEC is unknown
+// (for example connection is lost), error is returned and neither commit nor rollback hooks run
+func (rt *redisTransactor) CommitCtx(ctx context.Context) error {
+	scope, ok := getScope(ctx, txOwnerOf(rt.client))
+	if !ok || !scope.owner {
+		return nil
+	}
+
+	if scope.state != nil && scope.state.rollbackOnly.Load() {
+		scope.tx.Discard()
+		scope.hooks.RunRollback()
+		return storage.ErrTransactionRollbackOnly
+	}
+
+	if _, err := scope.tx.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		if isDiscarded(err) {
+			scope.hooks.RunRollback()
+		}
+
+		return ErrTransactorCommit.SetError(err)
+	}
+
+	scope.hooks.RunCommit()
+	return nil
+}
+
+// isDiscarded checks if EXEC error means redis did not apply any command of transaction
+func isDiscarded(err error) bool {
+	if errors.Is(err, redis.TxFailedErr) {
+		return true
+	}
+
+	var replyErr redis.Error
+	return errors.As(err, &replyErr) && strings.HasPrefix(replyErr.Error(), "EXECABORT")
+}
+
+// RollbackCtx discards commands queued in transaction.
+//
+// Joined transaction marks transaction as "rollback only", so transactor which began it discards commands on commit.
+// If joined transaction was set by SetTx, its owner is unknown and storage.ErrTransactionNotOwned is returned
+// without discarding commands
+func (rt *redisTransactor) RollbackCtx(ctx context.Context) error {
+	scope, ok := getScope(ctx, txOwnerOf(rt.client))
+	if !ok {
+		return nil
+	}
+
+	if !scope.owner {
+		// joined scope never discards transaction it does not own
+		if scope.state == nil {
+			return storage.ErrTransactionNotOwned
+		}
+
+		scope.state.rollbackOnly.Store(true)
+		return nil
+	}
+
+	scope.tx.Discard()
+	scope.hooks.RunRollback()
+	return nil
+}
+
+func (rt *redisTransactor) TryCommit(ctx context.Context, err *error) {
+	if err != nil && *err != nil {
+		_ = rt.RollbackCtx(ctx)
+		return
+	}
+
+	_ = rt.CommitCtx(ctx)
+}
+
+// txScope describes part of transaction started by single BeginCtx call.
+//
+// Transaction set by SetTx has scope without state: it is owned by the code which set it
+type txScope struct {
+	tx redis.Pipeliner
+	// shard is key of shard where pipeline of shard client is bound, empty if shard is unknown
+	shard string
+	owner bool
+	state *txState
+	hooks *storage.Hooks
+}
+
+// txState is shared state of transaction and all scopes which joined it
+type txState struct {
+	rollbackOnly atomic.Bool
+}
+
+// txScopes contains transaction scopes of clients by their owner.
+//
+// Scopes of all clients are kept by one context key, so they can be copied by storage.RewriteTx
+type txScopes map[any]*txScope
+
+// setScope sets scope of client transaction to new context. Scopes of other clients are kept
+func setScope(ctx context.Context, owner any, scope *txScope) context.Context {
+	scopes, _ := ctx.Value(transactionKey).(txScopes)
+	next := make(txScopes, len(scopes)+1)
+	maps.Copy(next, scopes)
+	next[owner] = scope
+	return context.WithValue(ctx, transactionKey, next)
+}
+
+// getScope returns scope of client transaction from context if it exist
+func getScope(ctx context.Context, owner any) (*txScope, bool) {
+	scopes, _ := ctx.Value(transactionKey).(txScopes)
+	scope, ok := scopes[owner]
+	return scope, ok && scope != nil
+}
+
+type redisTransaction struct {
+	transactor *redisTransactor
+	ctx        context.Context
+}
+
+func newTransaction(ctx context.Context, transactor *redisTransactor) storage.Transaction {
+	return &redisTransaction{
+		transactor: transactor,
+		ctx:        ctx,
+	}
+}
+
+func (tx *redisTransaction) Commit(_ context.Context) error {
+	return tx.transactor.CommitCtx(tx.ctx)
+}
+
+func (tx *redisTransaction) Rollback(_ context.Context) error {
+	return tx.transactor.RollbackCtx(tx.ctx)
+}
+
+func (tx *redisTransaction) Context() context.Context {
+	return tx.ctx
+}
@@ -0,0 +1,415 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/boostgo/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestClient creates client which is never connected: transaction pipelines do not connect until EXEC
+func newTestClient(t *testing.T) Client {
+	conn := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return NewFromClient(conn)
+}
+
+func TestTransactorPropagation(t *testing.T) {
+	tests := []struct {
+		name        string
+		propagation storage.Propagation
+		outer       bool
+		wantTx      bool
+		wantJoined  bool
+		wantErr     error
+	}{
+		{
+			name:        "required creates transaction",
+			propagation: storage.PropagationRequired,
+			wantTx:      true,
+		},
+		{
+			name:        "required joins transaction",
+			propagation: storage.PropagationRequired,
+			outer:       true,
+			wantTx:      true,
+			wantJoined:  true,
+		},
+		{
+			name:        "requires new creates transaction",
+			propagation: storage.PropagationRequiresNew,
+			outer:       true,
+			wantTx:      true,
+		},
+		{
+			name:        "supports without transaction",
+			propagation: storage.PropagationSupports,
+		},
+		{
+			name:        "mandatory without transaction",
+			propagation: storage.PropagationMandatory,
+			wantErr:     storage.ErrTransactionRequired,
+		},
+		{
+			name:        "never inside transaction",
+			propagation: storage.PropagationNever,
+			outer:       true,
+			wantErr:     storage.ErrTransactionNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			transactor := NewTransactor(client)
+
+			ctx := context.Background()
+			if tt.outer {
+				var err error
+				ctx, err = transactor.BeginCtx(ctx)
+				if err != nil {
+					t.Fatalf("begin outer transaction: %v", err)
+				}
+			}
+			outer, _ := GetTx(ctx, client)
+
+			txCtx, err := transactor.BeginCtx(storage.SetPropagation(ctx, tt.propagation))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			tx, ok := GetTx(txCtx, client)
+			if ok != tt.wantTx {
+				t.Fatalf("expected transaction in context: %v", tt.wantTx)
+			}
+			if tt.outer && (tx == outer) != tt.wantJoined {
+				t.Fatalf("expected joined transaction: %v", tt.wantJoined)
+			}
+		})
+	}
+}
+
+func TestTransactionBelongsToClient(t *testing.T) {
+	first := newTestClient(t)
+	second := newTestClient(t)
+
+	ctx, err := NewTransactor(first).BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin first: %v", err)
+	}
+
+	if _, ok := GetTx(ctx, second); ok {
+		t.Fatal("second client must not see transaction of first client")
+	}
+
+	// second transactor begins its own transaction instead of joining transaction of first client
+	ctx, err = NewTransactor(second).BeginCtx(ctx)
+	if err != nil {
+		t.Fatalf("begin second: %v", err)
+	}
+
+	firstTx, _ := GetTx(ctx, first)
+	secondTx, _ := GetTx(ctx, second)
+	if firstTx == nil || secondTx == nil || firstTx == secondTx {
+		t.Fatal("expected separate transaction of every client")
+	}
+
+	if err = first.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("queue command: %v", err)
+	}
+	if firstTx.Len() != 1 || secondTx.Len() != 0 {
+		t.Fatal("command must be queued to transaction of its client")
+	}
+
+	// clients over the same connection share transaction
+	same := NewFromClient(first.(*singleClient).client.(*redis.Client))
+	if tx, _ := GetTx(ctx, same); tx != firstTx {
+		t.Fatal("expected transaction shared by clients of the same connection")
+	}
+}
+
+func TestReadInsideTransaction(t *testing.T) {
+	client := newTestClient(t)
+	ctx, err := NewTransactor(client).BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		read func() error
+	}{
+		{
+			name: "get",
+			read: func() error {
+				_, err := client.Get(ctx, "key")
+				return err
+			},
+		},
+		{
+			name: "exist",
+			read: func() error {
+				_, err := client.Exist(ctx, "key")
+				return err
+			},
+		},
+		{
+			name: "parse",
+			read: func() error {
+				var value map[string]any
+				return client.Parse(ctx, "key", &value)
+			},
+		},
+		{
+			name: "hash get all",
+			read: func() error {
+				_, err := client.HGetAll(ctx, "key")
+				return err
+			},
+		},
+		{
+			name: "scan",
+			read: func() error {
+				_, _, err := client.Scan(ctx, 0, "*", 10)
+				return err
+			},
+		},
+		{
+			name: "set if not exist",
+			read: func() error {
+				_, err := client.SetNX(ctx, "lock", "owner", time.Second)
+				return err
+			},
+		},
+		{
+			name: "hash increment",
+			read: func() error {
+				_, err := client.HIncrBy(ctx, "key", "field", 1)
+				return err
+			},
+		},
+		{
+			name: "hash float increment",
+			read: func() error {
+				_, err := client.HIncrByFloat(ctx, "key", "field", 1.5)
+				return err
+			},
+		},
+		{
+			name: "hash expire",
+			read: func() error {
+				_, err := client.HExpire(ctx, "key", time.Second, "field")
+				return err
+			},
+		},
+		{
+			name: "eval",
+			read: func() error {
+				_, err := client.Eval(ctx, "return 1", []string{"key"})
+				return err
+			},
+		},
+		{
+			name: "eval sha",
+			read: func() error {
+				_, err := client.EvalSha(ctx, "sha", []string{"key"})
+				return err
+			},
+		},
+		{
+			name: "script load",
+			read: func() error {
+				_, err := client.ScriptLoad(ctx, "return 1")
+				return err
+			},
+		},
+		{
+			name: "script flush",
+			read: func() error {
+				_, err := client.ScriptFlush(ctx)
+				return err
+			},
+		},
+		{
+			name: "script kill",
+			read: func() error {
+				_, err := client.ScriptKill(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.read(); !errors.Is(err, ErrTransactionRead) {
+				t.Fatalf("expected transaction read error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJoinedRollbackMarksRollbackOnly(t *testing.T) {
+	client := newTestClient(t)
+	transactor := NewTransactor(client)
+
+	ctx, err := transactor.BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	joined, err := transactor.BeginCtx(ctx)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if err = client.Set(joined, "key", "value"); err != nil {
+		t.Fatalf("queue command: %v", err)
+	}
+
+	if err = transactor.RollbackCtx(joined); err != nil {
+		t.Fatalf("rollback joined: %v", err)
+	}
+
+	// commit discards commands without connecting to redis
+	if err = transactor.CommitCtx(ctx); !errors.Is(err, storage.ErrTransactionRollbackOnly) {
+		t.Fatalf("expected rollback only error, got %v", err)
+	}
+}
+
+func TestJoinedRollbackDoesNotDiscardForeignTransaction(t *testing.T) {
+	client := newTestClient(t)
+
+	pipe, err := client.TxPipeline(context.Background())
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	pipe.Set(context.Background(), "key", "value", 0)
+
+	transactor := NewTransactor(client)
+	joined, err := transactor.BeginCtx(SetTx(context.Background(), client, pipe))
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if err = transactor.RollbackCtx(joined); !errors.Is(err, storage.ErrTransactionNotOwned) {
+		t.Fatalf("expected not owned error, got %v", err)
+	}
+
+	if pipe.Len() != 1 {
+		t.Fatal("queued commands were discarded")
+	}
+}
+
+// replyError is error reply of redis
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+func (e replyError) RedisError()   {}
+
+func TestIsDiscarded(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "failed watch", err: redis.TxFailedErr, want: true},
+		{name: "aborted", err: replyError("EXECABORT Transaction discarded because of previous errors."), want: true},
+		{name: "command failed after exec", err: replyError("WRONGTYPE Operation against a key holding the wrong kind of value")},
+		{name: "connection lost", err: &net.OpError{Op: "read", Err: errors.New("connection reset")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDiscarded(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommitUnknownResultRunsNoHooks(t *testing.T) {
+	client := newTestClient(t)
+	transactor := NewTransactor(client)
+
+	ctx, err := transactor.BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	var hooks []string
+	_ = storage.OnCommit(ctx, func(ctx context.Context) error {
+		hooks = append(hooks, "commit")
+		return nil
+	})
+	_ = storage.OnRollback(ctx, func(ctx context.Context) error {
+		hooks = append(hooks, "rollback")
+		return nil
+	})
+
+	if err = client.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("queue command: %v", err)
+	}
+
+	// client is never connected, so it is unknown if EXEC is applied
+	if err = transactor.CommitCtx(ctx); !errors.Is(err, ErrTransactorCommit) {
+		t.Fatalf("expected commit error, got %v", err)
+	}
+
+	if len(hooks) != 0 {
+		t.Fatalf("expected no hooks to run, got %v", hooks)
+	}
+}
+
+func TestShardTransactionShard(t *testing.T) {
+	clients := newClients([]ShardClient{newTestShard(t, "a"), newTestShard(t, "b")}, LookupSelector(map[string]string{
+		"a": "a",
+		"b": "b",
+	}))
+	client := NewShard(clients)
+	transactor := NewTransactor(client)
+
+	ctx, err := transactor.BeginCtx(storage.WithShardKey(context.Background(), "a"))
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	joined, err := transactor.BeginCtx(ctx)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		shardKey string
+		wantErr  error
+	}{
+		{name: "shard of transaction", ctx: ctx, shardKey: "a"},
+		{name: "another shard", ctx: ctx, shardKey: "b", wantErr: ErrTxShardMismatch},
+		{name: "joined transaction at another shard", ctx: joined, shardKey: "b", wantErr: ErrTxShardMismatch},
+		{name: "unknown shard", ctx: ctx, shardKey: "c", wantErr: storage.ErrShardNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.Set(storage.WithShardKey(tt.ctx, tt.shardKey), "key", "value")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err = transactor.RollbackCtx(ctx); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/boostgo/errorx"
+)
+
+const sagaStateKey = "STORAGE_SAGA_STATE"
+
+// DefaultSagaLease is default time after the last saved progress when saga is considered as crashed and can be recovered
+const DefaultSagaLease = time.Minute
+
+// SagaStatus is status of saga progress
+type SagaStatus string
+
+const (
+	// SagaRunning means saga runs its steps
+	SagaRunning SagaStatus = "running"
+	// SagaCompleted means all saga steps are done
+	SagaCompleted SagaStatus = "completed"
+	// SagaCompensating means one of the steps failed and saga compensates done steps
+	SagaCompensating SagaStatus = "compensating"
+	// SagaCompensated means all done steps are compensated
+	SagaCompensated SagaStatus = "compensated"
+	// SagaFailed means one of compensations failed. Saga can be compensated again by Saga.Compensate
+	SagaFailed SagaStatus = "failed"
+)
+
+// SagaState describes progress of single saga run
+type SagaState struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+	// Status of saga
+	Status SagaStatus `db:"status"`
+	// Step is count of done steps. If saga compensates, steps before Step are still not compensated
+	Step int `db:"step"`
+	// Payload is data provided to Saga.Run. Can be used by steps after restart
+	Payload []byte `db:"payload"`
+	// Error is error text of the failed step or compensation
+	Error     string    `db:"error"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// SagaStore persists saga progress, so crashed saga can be resumed or compensated after restart
+type SagaStore interface {
+	// Save creates or updates saga state
+	Save(ctx context.Context, state SagaState) error
+	// Pending returns states of sagas with provided name which are not completed or compensated yet
+	// and were updated before provided time
+	Pending(ctx context.Context, name string, updatedBefore time.Time) ([]SagaState, error)
+	// Claim takes saga for recovery by setting its update time to claimedAt.
+	// State is updated only if it was not updated since it was loaded (compare-and-set by UpdatedAt).
+	// Returns false if saga was claimed or updated by another process
+	Claim(ctx context.Context, state SagaState, claimedAt time.Time) (bool, error)
+}
+
+// SagaAction is action or compensation of saga step
+type SagaAction func(ctx context.Context) error
+
+type sagaStep struct {
+	name         string
+	action       SagaAction
+	compensation SagaAction
+	transactor   Transactor
+}
+
+// Saga runs steps which may use different storages one by one.
+//
+// Atomicity across storages is impossible, so if any step fails,
+// compensations of all done steps run in reverse order
+type Saga struct {
+	name  string
+	steps []sagaStep
+	store SagaStore
+	lease time.Duration
+}
+
+// NewSaga creates saga builder.
+//
+// Name is used to find pending sagas in SagaStore, so it must be unique for every saga
+func NewSaga(name string) *Saga {
+	return &Saga{
+		name:  name,
+		steps: make([]sagaStep, 0),
+		lease: DefaultSagaLease,
+	}
+}
+
+// Step adds step with action and compensation. Compensation can be nil if step does not need it
+func (s *Saga) Step(name string, action, compensation SagaAction) *Saga {
+	s.steps = append(s.steps, sagaStep{
+		name:         name,
+		action:       action,
+		compensation: compensation,
+	})
+	return s
+}
+
+// StepTx adds step which action & compensation run inside transaction of provided transactor
+func (s *Saga) StepTx(name string, transactor Transactor, action, compensation SagaAction) *Saga {
+	s.steps = append(s.steps, sagaStep{
+		name:         name,
+		action:       action,
+		compensation: compensation,
+		transactor:   transactor,
+	})
+	return s
+}
+
+// Store sets store which persists saga progress after every step
+func (s *Saga) Store(store SagaStore) *Saga {
+	s.store = store
+	return s
+}
+
+// Lease sets time after the last saved progress when saga is considered as crashed and can be recovered by Recover.
+//
+// Saga progress is saved after every step, so lease must be longer than the longest step
+func (s *Saga) Lease(lease time.Duration) *Saga {
+	s.lease = lease
+	return s
+}
+
+// Run runs all saga steps with provided payload.
+//
+// If one of the steps fails, done steps are compensated and error of the step is returned
+func (s *Saga) Run(ctx context.Context, payload []byte) error {
+	id, err := newSagaID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.run(ctx, SagaState{
+		ID:        id,
+		Name:      s.name,
+		Status:    SagaRunning,
+		Payload:   payload,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// Resume continues saga from provided state (usually loaded from SagaStore after restart).
+//
+// Running saga continues from the first not done step, so steps must be idempotent.
+// Compensating or failed saga continues compensation
+func (s *Saga) Resume(ctx context.Context, state SagaState) error {
+	switch state.Status {
+	case SagaRunning:
+		return s.run(ctx, state)
+	case SagaCompensating, SagaFailed:
+		return s.Compensate(ctx, state)
+	default:
+		return nil
+	}
+}
+
+// Compensate runs compensations of all done steps from provided state in reverse order
+func (s *Saga) Compensate(ctx context.Context, state SagaState) error {
+	state.Status = SagaCompensating
+	if state.Step > len(s.steps) {
+		state.Step = len(s.steps)
+	}
+
+	if err := s.save(ctx, &state); err != nil {
+		return err
+	}
+
+	for state.Step > 0 {
+		step := s.steps[state.Step-1]
+		if step.compensation != nil {
+			// state is set to context of every step, so compensation sees its own step
+			stepCtx := context.WithValue(ctx, sagaStateKey, state)
+			if err := s.runStep(stepCtx, step, step.compensation); err != nil {
+				state.Status = SagaFailed
+				state.Error = err.Error()
+				_ = s.save(ctx, &state)
+				return ErrSagaCompensation.
+					SetError(err).
+					SetParams(s.stepParams(step))
+			}
+		}
+
+		state.Step--
+		if err := s.save(ctx, &state); err != nil {
+			return err
+		}
+	}
+
+	state.Status = SagaCompensated
+	return s.save(ctx, &state)
+}
+
+// Recover loads pending sagas which progress was not saved for the lease time (see Lease)
+// and resumes them (if "resume" is true) or compensates.
+//
+// Every saga is claimed before recovery, so sagas are not recovered by several processes at once.
+// Usually called on application start or periodically
+func (s *Saga) Recover(ctx context.Context, resume bool) error {
+	if s.store == nil {
+		return nil
+	}
+
+	states, err := s.store.Pending(ctx, s.name, time.Now().Add(-s.lease))
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		claimedAt := time.Now()
+		claimed, err := s.store.Claim(ctx, state, claimedAt)
+		if err != nil {
+			return ErrSagaClaim.
+				SetError(err).
+				SetParams([]errorx.Parameter{
+					{Key: "saga", Value: s.name},
+					{Key: "id", Value: state.ID},
+				})
+		}
+
+		// saga is recovered or still run by another process
+		if !claimed {
+			continue
+		}
+
+		state.UpdatedAt = claimedAt
+		if resume {
+			err = s.Resume(ctx, state)
+		} else {
+			err = s.Compensate(ctx, state)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Saga) run(ctx context.Context, state SagaState) error {
+	if err := s.save(ctx, &state); err != nil {
+		return err
+	}
+
+	for state.Step < len(s.steps) {
+		step := s.steps[state.Step]
+		// state is set to context of every step, so action sees its own step
+		stepCtx := context.WithValue(ctx, sagaStateKey, state)
+		if err := s.runStep(stepCtx, step, step.action); err != nil {
+			state.Error = err.Error()
+			if compensateErr := s.Compensate(ctx, state); compensateErr != nil {
+				return compensateErr
+			}
+
+			return ErrSagaStep.
+				SetError(err).
+				SetParams(s.stepParams(step))
+		}
+
+		state.Step++
+		if err := s.save(ctx, &state); err != nil {
+			return err
+		}
+	}
+
+	state.Status = SagaCompleted
+	return s.save(ctx, &state)
+}
+
+// runStep runs action of the step inside transaction if step has transactor
+func (s *Saga) runStep(ctx context.Context, step sagaStep, action SagaAction) (err error) {
+	if step.transactor == nil {
+		return errorx.Try(func() error {
+			return action(ctx)
+		})
+	}
+
+	ctx, err = step.transactor.BeginCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = errorx.Try(func() error {
+		return action(ctx)
+	})
+	if err != nil {
+		_ = step.transactor.RollbackCtx(ctx)
+		return err
+	}
+
+	return step.transactor.CommitCtx(ctx)
+}
+
+func (s *Saga) save(ctx context.Context, state *SagaState) error {
+	if s.store == nil {
+		return nil
+	}
+
+	state.UpdatedAt = time.Now()
+	if err := s.store.Save(ctx, *state); err != nil {
+		return ErrSagaSave.
+			SetError(err).
+			AddParam("saga", s.name)
+	}
+
+	return nil
+}
+
+func (s *Saga) stepParams(step sagaStep) []errorx.Parameter {
+	return []errorx.Parameter{
+		{Key: "saga", Value: s.name},
+		{Key: "step", Value: step.name},
+	}
+}
+
+// GetSagaState returns state of the saga which runs the current step
+func GetSagaState(ctx context.Context) (SagaState, bool) {
+	state, ok := ctx.Value(sagaStateKey).(SagaState)
+	return state, ok
+}
+
+func newSagaID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySagaStore keeps saga states in memory
+type memorySagaStore struct {
+	mx     sync.Mutex
+	states map[string]SagaState
+	// claimedBy imitates another process which claims saga before Claim call
+	claimedBy func(state SagaState) bool
+}
+
+func newMemorySagaStore(states ...SagaState) *memorySagaStore {
+	store := &memorySagaStore{
+		states: make(map[string]SagaState),
+	}
+	for _, state := range states {
+		store.states[state.ID] = state
+	}
+
+	return store
+}
+
+func (store *memorySagaStore) Save(_ context.Context, state SagaState) error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	store.states[state.ID] = state
+	return nil
+}
+
+func (store *memorySagaStore) Pending(_ context.Context, name string, updatedBefore time.Time) ([]SagaState, error) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	pending := make([]SagaState, 0)
+	for _, state := range store.states {
+		if state.Name != name || !state.UpdatedAt.Before(updatedBefore) {
+			continue
+		}
+
+		switch state.Status {
+		case SagaRunning, SagaCompensating, SagaFailed:
+			pending = append(pending, state)
+		}
+	}
+
+	slices.SortFunc(pending, func(a, b SagaState) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return pending, nil
+}
+
+func (store *memorySagaStore) Claim(_ context.Context, state SagaState, claimedAt time.Time) (bool, error) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	current := store.states[state.ID]
+	if store.claimedBy != nil && store.claimedBy(state) {
+		current.UpdatedAt = claimedAt
+		store.states[state.ID] = current
+	}
+
+	if !current.UpdatedAt.Equal(state.UpdatedAt) {
+		return false, nil
+	}
+
+	current.UpdatedAt = claimedAt
+	store.states[state.ID] = current
+	return true, nil
+}
+
+func TestSagaRun(t *testing.T) {
+	fail := errors.New("step failed")
+
+	tests := []struct {
+		name       string
+		failAction string
+		failComp   string
+		wantCalls  []string
+		wantStatus SagaStatus
+		wantErr    error
+	}{
+		{
+			name:       "completed",
+			wantCalls:  []string{"a", "b", "c"},
+			wantStatus: SagaCompleted,
+		},
+		{
+			name:       "compensated in reverse order",
+			failAction: "c",
+			wantCalls:  []string{"a", "b", "c", "undo b", "undo a"},
+			wantStatus: SagaCompensated,
+			wantErr:    ErrSagaStep,
+		},
+		{
+			name:       "compensation failed",
+			failAction: "c",
+			failComp:   "undo a",
+			wantCalls:  []string{"a", "b", "c", "undo b", "undo a"},
+			wantStatus: SagaFailed,
+			wantErr:    ErrSagaCompensation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			action := func(name string) SagaAction {
+				return func(ctx context.Context) error {
+					calls = append(calls, name)
+					if name == tt.failAction || name == tt.failComp {
+						return fail
+					}
+
+					return nil
+				}
+			}
+
+			store := newMemorySagaStore()
+			err := NewSaga("test").
+				Step("a", action("a"), action("undo a")).
+				Step("b", action("b"), action("undo b")).
+				Step("c", action("c"), action("undo c")).
+				Store(store).
+				Run(context.Background(), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Fatalf("expected calls %v, got %v", tt.wantCalls, calls)
+			}
+
+			for _, state := range store.states {
+				if state.Status != tt.wantStatus {
+					t.Fatalf("expected status %s, got %s", tt.wantStatus, state.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestSagaStepState(t *testing.T) {
+	tests := []struct {
+		name       string
+		failAction string
+		// wantStates are "call status step" seen by GetSagaState inside every action
+		wantStates []string
+	}{
+		{
+			name:       "run",
+			wantStates: []string{"a running 0", "b running 1", "c running 2"},
+		},
+		{
+			name:       "compensate",
+			failAction: "c",
+			wantStates: []string{"a running 0", "b running 1", "c running 2", "undo b compensating 2", "undo a compensating 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var states []string
+			action := func(name string) SagaAction {
+				return func(ctx context.Context) error {
+					state, ok := GetSagaState(ctx)
+					if !ok {
+						return errors.New("saga state is missing")
+					}
+
+					states = append(states, fmt.Sprintf("%s %s %d", name, state.Status, state.Step))
+					if name == tt.failAction {
+						return errors.New("step failed")
+					}
+
+					return nil
+				}
+			}
+
+			_ = NewSaga("test").
+				Step("a", action("a"), action("undo a")).
+				Step("b", action("b"), action("undo b")).
+				Step("c", action("c"), action("undo c")).
+				Run(context.Background(), nil)
+
+			if !slices.Equal(states, tt.wantStates) {
+				t.Fatalf("expected states %v, got %v", tt.wantStates, states)
+			}
+		})
+	}
+}
+
+func TestSagaRecover(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		states    []SagaState
+		claimedBy func(state SagaState) bool
+		resume    bool
+		wantCalls []string
+	}{
+		{
+			name: "resume expired saga",
+			states: []SagaState{
+				{ID: "1", Name: "test", Status: SagaRunning, Step: 1, UpdatedAt: expired},
+			},
+			resume:    true,
+			wantCalls: []string{"b"},
+		},
+		{
+			name: "compensate expired saga",
+			states: []SagaState{
+				{ID: "1", Name: "test", Status: SagaRunning, Step: 1, UpdatedAt: expired},
+			},
+			wantCalls: []string{"undo a"},
+		},
+		{
+			name: "skip saga within lease",
+			states: []SagaState{
+				{ID: "1", Name: "test", Status: SagaRunning, Step: 1, UpdatedAt: now},
+			},
+			resume: true,
+		},
+		{
+			name: "skip finished sagas",
+			states: []SagaState{
+				{ID: "1", Name: "test", Status: SagaCompleted, Step: 2, UpdatedAt: expired},
+				{ID: "2", Name: "test", Status: SagaCompensated, UpdatedAt: expired},
+			},
+			resume: true,
+		},
+		{
+			name: "skip saga claimed by another process",
+			states: []SagaState{
+				{ID: "1", Name: "test", Status: SagaRunning, Step: 1, UpdatedAt: expired, CreatedAt: expired},
+				{ID: "2", Name: "test", Status: SagaCompensating, Step: 1, UpdatedAt: expired, CreatedAt: now},
+			},
+			claimedBy: func(state SagaState) bool {
+				return state.ID == "1"
+			},
+			resume:    true,
+			wantCalls: []string{"undo a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			action := func(name string) SagaAction {
+				return func(ctx context.Context) error {
+					calls = append(calls, name)
+					return nil
+				}
+			}
+
+			store := newMemorySagaStore(tt.states...)
+			store.claimedBy = tt.claimedBy
+
+			err := NewSaga("test").
+				Step("a", action("a"), action("undo a")).
+				Step("b", action("b"), action("undo b")).
+				Store(store).
+				Lease(time.Minute).
+				Recover(context.Background(), tt.resume)
+			if err != nil {
+				t.Fatalf("recover: %v", err)
+			}
+
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Fatalf("expected calls %v, got %v", tt.wantCalls, calls)
+			}
+		})
+	}
+}
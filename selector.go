@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/dgryski/go-rendezvous"
+)
+
+// Shard is shard connection which can be chosen by selector
+type Shard interface {
+	Key() string
+}
+
+// Selector chooses shard for query. Returns nil if shard is not chosen
+type Selector[T Shard] func(ctx context.Context, shards []T) T
+
+// NumericRange is range of numeric shard keys [From, To) which belong to shard with key Shard
+type NumericRange struct {
+	From  int64
+	To    int64
+	Shard string
+}
+
+// LexicalRange is range of shard keys [From, To) compared as strings which belong to shard with key Shard.
+//
+// Empty To means range has no upper bound
+type LexicalRange struct {
+	From  string
+	To    string
+	Shard string
+}
+
+// HashSelector chooses shard by consistent hashing of shard key from context (see WithShardKey).
+//
+// Every shard is placed to hash ring virtualNodes times, so keys are spread evenly
+// and adding or removing shard moves only keys of this shard
+func HashSelector[T Shard](virtualNodes int) Selector[T] {
+	const defaultVirtualNodes = 128
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	var cached atomic.Pointer[hashRing]
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok || len(shards) == 0 {
+			return zero
+		}
+
+		ring := cached.Load()
+		if ring == nil || !ringMatch(ring, shards) {
+			ring = newHashRing(shards, virtualNodes)
+			cached.Store(ring)
+		}
+
+		return shards[ring.lookup(key)]
+	}
+}
+
+// RendezvousSelector chooses shard by rendezvous (highest random weight) hashing of shard key from context.
+//
+// Adding or removing shard moves only keys of this shard
+func RendezvousSelector[T Shard]() Selector[T] {
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok || len(shards) == 0 {
+			return zero
+		}
+
+		keys := make([]string, len(shards))
+		for idx, shard := range shards {
+			keys[idx] = shard.Key()
+		}
+
+		return findShard(ctx, shards, rendezvous.New(keys, xxhash.Sum64String).Lookup(key))
+	}
+}
+
+// ModuloSelector chooses shard by remainder of division numeric shard key from context by count of shards.
+//
+// Result depends on order of shards, so adding or removing shard moves most of the keys
+func ModuloSelector[T Shard]() Selector[T] {
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok || len(shards) == 0 {
+			return zero
+		}
+
+		number, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			FailSelection(ctx, ErrShardKeyInvalid.
+				SetError(err).
+				AddParam("shard_key", key))
+			return zero
+		}
+
+		return shards[number%uint64(len(shards))]
+	}
+}
+
+// NumericRangeSelector chooses shard which range contain numeric shard key from context
+func NumericRangeSelector[T Shard](ranges ...NumericRange) Selector[T] {
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok {
+			return zero
+		}
+
+		number, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			FailSelection(ctx, ErrShardKeyInvalid.
+				SetError(err).
+				AddParam("shard_key", key))
+			return zero
+		}
+
+		for _, r := range ranges {
+			if number >= r.From && number < r.To {
+				return findShard(ctx, shards, r.Shard)
+			}
+		}
+
+		FailSelection(ctx, ErrShardNotFound.AddParam("shard_key", key))
+		return zero
+	}
+}
+
+// LexicalRangeSelector chooses shard which range contain shard key from context
+func LexicalRangeSelector[T Shard](ranges ...LexicalRange) Selector[T] {
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok {
+			return zero
+		}
+
+		for _, r := range ranges {
+			if key >= r.From && (r.To == "" || key < r.To) {
+				return findShard(ctx, shards, r.Shard)
+			}
+		}
+
+		FailSelection(ctx, ErrShardNotFound.AddParam("shard_key", key))
+		return zero
+	}
+}
+
+// LookupSelector chooses shard by static map of shard keys to keys of shards
+func LookupSelector[T Shard](lookup map[string]string) Selector[T] {
+	return func(ctx context.Context, shards []T) T {
+		var zero T
+
+		key, ok := shardKey(ctx)
+		if !ok {
+			return zero
+		}
+
+		shard, ok := lookup[key]
+		if !ok {
+			FailSelection(ctx, ErrShardNotFound.AddParam("shard_key", key))
+			return zero
+		}
+
+		return findShard(ctx, shards, shard)
+	}
+}
+
+// shardKey returns shard key from context or reports missing key
+func shardKey(ctx context.Context) (string, bool) {
+	key, ok := GetShardKey(ctx)
+	if !ok {
+		FailSelection(ctx, ErrShardKeyMissing)
+		return "", false
+	}
+
+	return key, true
+}
+
+// findShard returns shard with provided key or reports that shard does not exist
+func findShard[T Shard](ctx context.Context, shards []T, key string) T {
+	for _, shard := range shards {
+		if shard.Key() == key {
+			return shard
+		}
+	}
+
+	FailSelection(ctx, ErrShardNotFound.AddParam("shard", key))
+
+	var zero T
+	return zero
+}
+
+// hashRing is consistent hashing ring of shard indexes
+type hashRing struct {
+	keys   []string
+	points []uint64
+	owners []int
+}
+
+func newHashRing[T Shard](shards []T, virtualNodes int) *hashRing {
+	type point struct {
+		hash  uint64
+		owner int
+	}
+
+	ring := &hashRing{
+		keys: make([]string, len(shards)),
+	}
+
+	points := make([]point, 0, len(shards)*virtualNodes)
+	for idx, shard := range shards {
+		ring.keys[idx] = shard.Key()
+		for node := 0; node < virtualNodes; node++ {
+			points = append(points, point{
+				hash:  xxhash.Sum64String(shard.Key() + "#" + strconv.Itoa(node)),
+				owner: idx,
+			})
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	ring.points = make([]uint64, len(points))
+	ring.owners = make([]int, len(points))
+	for idx, p := range points {
+		ring.points[idx] = p.hash
+		ring.owners[idx] = p.owner
+	}
+
+	return ring
+}
+
+// ringMatch checks if ring was built for the same shards
+func ringMatch[T Shard](ring *hashRing, shards []T) bool {
+	return slices.EqualFunc(ring.keys, shards, func(key string, shard T) bool {
+		return key == shard.Key()
+	})
+}
+
+// lookup returns index of shard owning provided key
+func (ring *hashRing) lookup(key string) int {
+	hash := xxhash.Sum64String(key)
+	idx := sort.Search(len(ring.points), func(i int) bool {
+		return ring.points[i] >= hash
+	})
+	if idx == len(ring.points) {
+		idx = 0
+	}
+
+	return ring.owners[idx]
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type testShard struct {
+	key        string
+	conditions []string
+}
+
+func (s *testShard) Key() string {
+	return s.key
+}
+
+func (s *testShard) Conditions() []string {
+	return s.conditions
+}
+
+func newTestShards(keys ...string) []*testShard {
+	shards := make([]*testShard, len(keys))
+	for idx, key := range keys {
+		shards[idx] = &testShard{key: key}
+	}
+
+	return shards
+}
+
+// selectShard runs selector with shard key and returns key of chosen shard and selection error
+func selectShard(selector Selector[*testShard], shards []*testShard, key *string) (string, error) {
+	ctx := context.Background()
+	if key != nil {
+		ctx = WithShardKey(ctx, *key)
+	}
+
+	ctx, selectionErr := TrackSelection(ctx)
+	shard := selector(ctx, shards)
+	if shard == nil {
+		return "", selectionErr()
+	}
+
+	return shard.Key(), nil
+}
+
+func TestSelectors(t *testing.T) {
+	key := func(key string) *string {
+		return &key
+	}
+
+	shards := newTestShards("a", "b", "c")
+
+	tests := []struct {
+		name      string
+		selector  Selector[*testShard]
+		key       *string
+		wantShard string
+		wantErr   error
+	}{
+		{name: "hash without key", selector: HashSelector[*testShard](0), wantErr: ErrShardKeyMissing},
+		{name: "rendezvous without key", selector: RendezvousSelector[*testShard](), wantErr: ErrShardKeyMissing},
+		{name: "modulo", selector: ModuloSelector[*testShard](), key: key("7"), wantShard: "b"},
+		{name: "modulo invalid key", selector: ModuloSelector[*testShard](), key: key("user"), wantErr: ErrShardKeyInvalid},
+		{name: "modulo without key", selector: ModuloSelector[*testShard](), wantErr: ErrShardKeyMissing},
+		{
+			name: "numeric range",
+			selector: NumericRangeSelector[*testShard](
+				NumericRange{From: 0, To: 100, Shard: "a"},
+				NumericRange{From: 100, To: 200, Shard: "c"},
+			),
+			key:       key("100"),
+			wantShard: "c",
+		},
+		{
+			name:     "numeric range not found",
+			selector: NumericRangeSelector[*testShard](NumericRange{From: 0, To: 100, Shard: "a"}),
+			key:      key("100"),
+			wantErr:  ErrShardNotFound,
+		},
+		{
+			name:     "numeric range invalid key",
+			selector: NumericRangeSelector[*testShard](NumericRange{From: 0, To: 100, Shard: "a"}),
+			key:      key("1.5"),
+			wantErr:  ErrShardKeyInvalid,
+		},
+		{
+			name:     "numeric range to missing shard",
+			selector: NumericRangeSelector[*testShard](NumericRange{From: 0, To: 100, Shard: "z"}),
+			key:      key("1"),
+			wantErr:  ErrShardNotFound,
+		},
+		{
+			name: "lexical range",
+			selector: LexicalRangeSelector[*testShard](
+				LexicalRange{From: "a", To: "m", Shard: "a"},
+				LexicalRange{From: "m", Shard: "b"},
+			),
+			key:       key("zebra"),
+			wantShard: "b",
+		},
+		{
+			name:      "lexical range lower bound",
+			selector:  LexicalRangeSelector[*testShard](LexicalRange{From: "m", To: "t", Shard: "c"}),
+			key:       key("m"),
+			wantShard: "c",
+		},
+		{
+			name:     "lexical range not found",
+			selector: LexicalRangeSelector[*testShard](LexicalRange{From: "m", To: "t", Shard: "c"}),
+			key:      key("t"),
+			wantErr:  ErrShardNotFound,
+		},
+		{
+			name:      "lookup",
+			selector:  LookupSelector[*testShard](map[string]string{"tenant-1": "c"}),
+			key:       key("tenant-1"),
+			wantShard: "c",
+		},
+		{
+			name:     "lookup not found",
+			selector: LookupSelector[*testShard](map[string]string{"tenant-1": "c"}),
+			key:      key("tenant-2"),
+			wantErr:  ErrShardNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shard, err := selectShard(tt.selector, shards, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if shard != tt.wantShard {
+				t.Fatalf("expected shard %q, got %q", tt.wantShard, shard)
+			}
+		})
+	}
+}
+
+func TestHashingSelectors(t *testing.T) {
+	const keys = 3000
+
+	tests := []struct {
+		name     string
+		selector func() Selector[*testShard]
+	}{
+		{name: "hash", selector: func() Selector[*testShard] { return HashSelector[*testShard](0) }},
+		{name: "rendezvous", selector: RendezvousSelector[*testShard]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := tt.selector()
+			shards := newTestShards("a", "b", "c")
+
+			before := make(map[string]string, keys)
+			counts := make(map[string]int)
+			for idx := range keys {
+				key := "user-" + strconv.Itoa(idx)
+				shard, err := selectShard(selector, shards, &key)
+				if err != nil {
+					t.Fatalf("select: %v", err)
+				}
+
+				before[key] = shard
+				counts[shard]++
+			}
+
+			// keys are spread evenly enough
+			for _, shard := range shards {
+				if counts[shard.Key()] < keys/len(shards)/2 {
+					t.Fatalf("expected keys to be spread evenly, got %v", counts)
+				}
+			}
+
+			// the same selector sees new shard and moves only keys to the new shard
+			shards = append(shards, &testShard{key: "d"})
+			moved := 0
+			for key, previous := range before {
+				shard, err := selectShard(selector, shards, &key)
+				if err != nil {
+					t.Fatalf("select: %v", err)
+				}
+
+				if shard == previous {
+					continue
+				}
+
+				if shard != "d" {
+					t.Fatalf("expected key %s to stay at %s or move to d, got %s", key, previous, shard)
+				}
+				moved++
+			}
+
+			if moved == 0 || moved > keys/2 {
+				t.Fatalf("expected about quarter of keys to move, moved %d", moved)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package storage
+
+import "context"
+
+const (
+	shardKeyKey       = "STORAGE_SHARD_KEY"
+	shardSelectionKey = "STORAGE_SHARD_SELECTION"
+)
+
+// WithShardKey sets shard key to new context. Key is used by built-in selectors to choose shard
+func WithShardKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, shardKeyKey, key)
+}
+
+// GetShardKey returns shard key from context if it exist
+func GetShardKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(shardKeyKey).(string)
+	return key, ok
+}
+
+type shardSelection struct {
+	err error
+}
+
+// TrackSelection sets to new context slot for error of shard selection.
+//
+// Used by shard connections before calling selector. Returned function returns error reported by selector
+func TrackSelection(ctx context.Context) (context.Context, func() error) {
+	selection := &shardSelection{}
+	return context.WithValue(ctx, shardSelectionKey, selection), func() error {
+		return selection.err
+	}
+}
+
+// FailSelection reports reason why selector did not choose shard.
+//
+// Selector still must return nil. Error is returned by shard connections instead of ErrConnNotSelected
+func FailSelection(ctx context.Context, err error) {
+	selection, ok := ctx.Value(shardSelectionKey).(*shardSelection)
+	if !ok {
+		return
+	}
+
+	selection.err = err
+}
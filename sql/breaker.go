@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/boostgo/storage"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type breakerConnect struct {
+	ShardConnect
+	breaker *storage.CircuitBreaker
+}
+
+// Breaker returns circuit breaker of shard connection
+func (conn *breakerConnect) Breaker() *storage.CircuitBreaker {
+	return conn.breaker
+}
+
+// WithCircuitBreaker guards every shard connection of Connections by its own circuit breaker and returns the same Connections.
+//
+// Shard client rejects queries to shard with open breaker by storage.ErrCircuitOpen without waiting for timeout.
+// If settings do not contain IsFailure, only connection errors are counted as failures
+// (query errors like constraint violations mean shard is available).
+// Shards added to Connections later are guarded too
+func WithCircuitBreaker(connections *Connections, settings storage.BreakerSettings) *Connections {
+	if settings.IsFailure == nil {
+		settings.IsFailure = IsConnectionFailure
+	}
+
+	connections.guardWith(func(conn ShardConnect) ShardConnect {
+		return &breakerConnect{
+			ShardConnect: conn,
+			breaker:      storage.NewCircuitBreaker("sql/"+conn.Key(), settings),
+		}
+	})
+	return connections
+}
+
+// AvailableConnections returns connections which breaker is not open.
+//
+// Can be used by selectors which may choose another shard if selected one is unavailable
+func AvailableConnections(connections []ShardConnect) []ShardConnect {
+	available := make([]ShardConnect, 0, len(connections))
+	for _, conn := range connections {
+		breaker, ok := storage.GetBreaker(conn)
+		if ok && breaker.State() == storage.BreakerOpen {
+			continue
+		}
+
+		available = append(available, conn)
+	}
+
+	return available
+}
+
+// IsConnectionFailure checks if error means database is unavailable.
+//
+// Failures are bad connections, network errors, errors of connecting and database errors
+// of classes 08 (connection exception), 53 (insufficient resources) & 57P (operator intervention).
+// Other errors are not failures: database responded or error is caused by the call itself
+func IsConnectionFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	code := ErrorCode(err)
+	return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53") || strings.HasPrefix(code, "57P")
+}
+
+// shardBreaker returns breaker of shard connection or nil if connection is not guarded
+func shardBreaker(conn ShardConnect) *storage.CircuitBreaker {
+	breaker, _ := storage.GetBreaker(conn)
+	return breaker
+}
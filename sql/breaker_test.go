@@ -0,0 +1,100 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+func TestIsConnectionFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "no rows", err: sql.ErrNoRows, want: false},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "wrapped network error", err: ErrTransactorBegin.SetError(&net.OpError{Op: "read", Err: errors.New("reset")}), want: true},
+		{name: "pgx connect error", err: &pgconn.ConnectError{}, want: true},
+		{name: "connection exception", err: &pq.Error{Code: "08006"}, want: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, want: true},
+		{name: "query canceled", err: &pq.Error{Code: "57014"}, want: false},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: false},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, want: false},
+		{name: "unknown error", err: errors.New("scan: converting type"), want: false},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConnectionFailure(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShardClientBreaker(t *testing.T) {
+	conn, mock := newMock(t)
+	connections := newConnections([]ShardConnect{newShardConnect("a", nil, conn)}, LookupSelector(map[string]string{
+		"a": "a",
+	}))
+	guarded := WithCircuitBreaker(connections, storage.BreakerSettings{FailureThreshold: 1})
+	if guarded != connections {
+		t.Fatal("expected connections to be guarded in place")
+	}
+
+	ctx := storage.WithShardKey(context.Background(), "a")
+	client := NewClientShard(guarded)
+
+	// query error does not open breaker
+	mock.ExpectExec("UPDATE users").WillReturnError(&pq.Error{Code: "23505"})
+	if _, err := client.ExecContext(ctx, "UPDATE users SET name = 'a'"); err == nil {
+		t.Fatal("expected query error")
+	}
+
+	mock.ExpectExec("UPDATE users").WillReturnError(&net.OpError{Op: "read", Err: errors.New("connection reset")})
+	if _, err := client.ExecContext(ctx, "UPDATE users SET name = 'a'"); err == nil {
+		t.Fatal("expected connection error")
+	}
+
+	// breaker is open, so query is not sent
+	if _, err := client.ExecContext(ctx, "UPDATE users SET name = 'a'"); !errors.Is(err, storage.ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+
+	row := client.QueryRowxContext(ctx, "SELECT id FROM users")
+	if row == nil {
+		t.Fatal("expected row, got nil")
+	}
+
+	var id int
+	if err := row.Scan(&id); !errors.Is(err, storage.ErrCircuitOpen) {
+		t.Fatalf("expected open circuit from row, got %v", err)
+	}
+
+	// connections without breaker are not guarded
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	plain := newConnections([]ShardConnect{newShardConnect("a", nil, conn)}, LookupSelector(map[string]string{
+		"a": "a",
+	}))
+	if _, err := NewClientShard(plain).ExecContext(ctx, "UPDATE users SET name = 'a'"); err != nil {
+		t.Fatalf("expected query to be sent, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,17 +3,218 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/boostgo/errorx"
+	"github.com/boostgo/storage"
 	"github.com/jmoiron/sqlx"
 )
 
+// ClientOption sets client settings
+type ClientOption func(options *clientOptions)
+
+type clientOptions struct {
+	logger       QueryLogger
+	slowQuery    *slowQuery
+	retry        *RetryPolicy
+	interceptors []Interceptor
+
+	// options of shard client
+	txShard TxShardMode
+
+	// options of replicated client
+	replicaBalance       ReplicaBalance
+	maxReplicationLag    time.Duration
+	replicaCheckInterval time.Duration
+}
+
+// LogOption enables printing queries by DefaultLogger
+func LogOption(enable bool) ClientOption {
+	return func(options *clientOptions) {
+		if !enable {
+			options.logger = nil
+			return
+		}
+
+		options.logger = DefaultLogger()
+	}
+}
+
+// LoggerOption sets logger which prints every query of client
+func LoggerOption(logger Logger) ClientOption {
+	return func(options *clientOptions) {
+		if logger == nil {
+			options.logger = nil
+			return
+		}
+
+		options.logger = loggerAdapter{
+			logger: logger,
+		}
+	}
+}
+
+// QueryLoggerOption sets logger which prints every query of client with its duration, result and error
+func QueryLoggerOption(logger QueryLogger) ClientOption {
+	return func(options *clientOptions) {
+		options.logger = logger
+	}
+}
+
+func newClientOptions(options ...ClientOption) clientOptions {
+	const defaultReplicaCheckInterval = time.Second * 5
+
+	opts := clientOptions{
+		replicaCheckInterval: defaultReplicaCheckInterval,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// handler returns chain of client interceptors.
+//
+// Tracing interceptor is the first one, so span covers all other interceptors.
+// Logging interceptor is the last one, so it prints query changed by other interceptors
+// and every retry attempt
+func (o clientOptions) handler() Handler {
+	interceptors := make([]Interceptor, 0, len(o.interceptors)+3)
+	interceptors = append(interceptors, TraceInterceptor)
+	interceptors = append(interceptors, o.interceptors...)
+	if o.retry != nil {
+		interceptors = append(interceptors, RetryInterceptor(*o.retry))
+	}
+	if o.logger != nil || o.slowQuery != nil {
+		interceptors = append(interceptors, o.logInterceptor)
+	}
+
+	return chain(interceptors, executeCall)
+}
+
+// logInterceptor prints finished query by logger if it is set and reports query if it is slow
+func (o clientOptions) logInterceptor(ctx context.Context, call *QueryCall, next Handler) error {
+	start := time.Now()
+	err := next(ctx, call)
+
+	entry := QueryLog{
+		Key:          call.Key,
+		QueryType:    call.Method,
+		Query:        call.Query,
+		Args:         call.Args,
+		Duration:     time.Since(start),
+		RowsAffected: call.rowsAffected(err),
+		Err:          err,
+	}
+
+	o.slowQuery.report(ctx, call.exec, entry)
+
+	if o.logger != nil && !storage.IsNoLog(ctx) {
+		o.logger.Log(ctx, entry)
+	}
+
+	return err
+}
+
 // NotFound check if provided error is not found error
 func NotFound(err error) bool {
 	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, errorx.ErrNotFound)
 }
 
+// eachConn runs fn with every shard connection if db is shard client or with db itself if it is single client
+func eachConn(db DB, fn func(conn DB) error) error {
+	if err := db.EachShard(fn); !errors.Is(err, ErrMethodNotSuppoertedInSingle) {
+		return err
+	}
+
+	return fn(db)
+}
+
+// executor is common part of connection and transaction
+type executor interface {
+	queryExecutor
+	BindNamed(query string, arg interface{}) (string, []interface{}, error)
+}
+
+// getExecutor returns transaction from context if it exist or provided connection
+func getExecutor(ctx context.Context, conn *sqlx.DB) executor {
+	if tx, ok := GetTx(ctx); ok {
+		return tx
+	}
+
+	return conn
+}
+
+// errRowDB is connection pool which can not connect. Created once on the first errRow call
+var (
+	errRowDB     *sqlx.DB
+	errRowDBOnce sync.Once
+)
+
+// errRowKey is context key of error which errRowDB returns on connect
+type errRowKey struct{}
+
+// errRow returns row which Scan returns provided error.
+//
+// sqlx does not allow to create row with error, so row is received from shared connection pool which can not connect:
+// its connector returns error passed by context of the query
+func errRow(err error) *sqlx.Row {
+	errRowDBOnce.Do(func() {
+		errRowDB = sqlx.NewDb(sql.OpenDB(errConnector{}), "")
+	})
+
+	return errRowDB.QueryRowxContext(context.WithValue(context.Background(), errRowKey{}, err), "")
+}
+
+// errConnector is connector which fails every connect by error from context
+type errConnector struct{}
+
+func (c errConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err, ok := ctx.Value(errRowKey{}).(error); ok {
+		return nil, err
+	}
+
+	return nil, sql.ErrConnDone
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return errDriver{}
+}
+
+type errDriver struct{}
+
+func (d errDriver) Open(string) (driver.Conn, error) {
+	return nil, sql.ErrConnDone
+}
+
+// rowsAffected returns count of affected rows by exec query result
+func rowsAffected(result sql.Result) int64 {
+	if result == nil {
+		return 0
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+
+	return affected
+}
+
+// rowsSelected returns count of rows scanned into select destination
+func rowsSelected(dest any) int64 {
+	value := reflect.Indirect(reflect.ValueOf(dest))
+	if value.Kind() != reflect.Slice {
+		return 0
+	}
+
+	return int64(value.Len())
+}
+
 // DB description of all methods of sqlx package.
 //
 // Can be used as single client & shard client
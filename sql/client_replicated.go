@@ -0,0 +1,336 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boostgo/contextx"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	primaryKey = "storage_sql_primary"
+
+	primaryConnectionKey = "primary"
+	replicaConnectionKey = "replica_"
+)
+
+// ReplicaBalance describes how replicated client chooses replica for read query
+type ReplicaBalance int
+
+const (
+	// ReplicaRoundRobin chooses replicas one by one
+	ReplicaRoundRobin ReplicaBalance = iota
+	// ReplicaLeastLatency chooses replica with the least latency of the last check
+	ReplicaLeastLatency
+)
+
+// ReplicaBalanceOption sets how replicated client chooses replica. By default, ReplicaRoundRobin is used
+func ReplicaBalanceOption(balance ReplicaBalance) ClientOption {
+	return func(options *clientOptions) {
+		options.replicaBalance = balance
+	}
+}
+
+// MaxReplicationLagOption sets max replication lag of replica. Replica with bigger lag is not used for reads.
+//
+// Lag is checked by pg_last_xact_replay_timestamp not often than once per interval.
+// Replica is not used until its lag is checked first time, so reads run on primary meanwhile
+func MaxReplicationLagOption(maxLag, interval time.Duration) ClientOption {
+	return func(options *clientOptions) {
+		options.maxReplicationLag = maxLag
+		if interval > 0 {
+			options.replicaCheckInterval = interval
+		}
+	}
+}
+
+// UsePrimary sets to new context flag which forces replicated client to run all queries on primary.
+//
+// Helps to read own writes which are not replicated yet
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// IsPrimary checks if context contain flag set by UsePrimary
+func IsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey).(bool)
+	return primary
+}
+
+// ReplicatedDB is DB which routes reads to replicas.
+//
+// Close stops background checks of replicas and waits for running ones. Connections are not closed
+type ReplicatedDB interface {
+	DB
+	Close() error
+}
+
+type clientReplicated struct {
+	primary  *sqlx.DB
+	replicas []*replica
+	options  clientOptions
+	handler  Handler
+	next     atomic.Uint64
+
+	// checks is context of background checks of replicas, it is canceled by Close
+	mx         sync.Mutex
+	checks     context.Context
+	stopChecks context.CancelFunc
+	running    sync.WaitGroup
+}
+
+// ClientReplicated creates DB implementation which routes reads to replicas and other queries to primary
+func ClientReplicated(primary *sqlx.DB, replicas ...*sqlx.DB) ReplicatedDB {
+	return NewClientReplicated(primary, replicas)
+}
+
+// NewClientReplicated creates DB implementation which routes reads to replicas and other queries to primary.
+//
+// SelectContext, GetContext & QueryxContext run on replica, other methods run on primary.
+// All queries run on primary inside transaction or with context created by UsePrimary.
+// If there are no available replicas, reads run on primary too
+func NewClientReplicated(primary *sqlx.DB, replicas []*sqlx.DB, options ...ClientOption) ReplicatedDB {
+	opts := newClientOptions(options...)
+
+	checks, stopChecks := context.WithCancel(context.Background())
+	client := &clientReplicated{
+		primary:    primary,
+		replicas:   make([]*replica, len(replicas)),
+		options:    opts,
+		handler:    opts.handler(),
+		checks:     checks,
+		stopChecks: stopChecks,
+	}
+
+	for idx, conn := range replicas {
+		client.replicas[idx] = newReplica(replicaConnectionKey+strconv.Itoa(idx), conn)
+	}
+
+	return client
+}
+
+func (c *clientReplicated) Connection() *sqlx.DB {
+	return c.primary
+}
+
+func (c *clientReplicated) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	call := &QueryCall{Method: MethodExec, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Result, err
+}
+
+func (c *clientReplicated) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	call := &QueryCall{Method: MethodQuery, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rows, err
+}
+
+func (c *clientReplicated) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	call := &QueryCall{Method: MethodQueryx, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rowsx, err
+}
+
+func (c *clientReplicated) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	call := &QueryCall{Method: MethodQueryRowx, Query: query, Args: args}
+	c.route(ctx, call)
+	return call.row(c.handler(ctx, call))
+}
+
+func (c *clientReplicated) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	call := &QueryCall{Method: MethodPrepare, Query: query}
+	err := c.call(ctx, call)
+	return call.Stmt, err
+}
+
+func (c *clientReplicated) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	call := &QueryCall{Method: MethodNamedExec, Query: query, Args: []any{arg}}
+	err := c.call(ctx, call)
+	return call.Result, err
+}
+
+func (c *clientReplicated) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.call(ctx, &QueryCall{Method: MethodSelect, Query: query, Args: args, Dest: dest})
+}
+
+func (c *clientReplicated) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.call(ctx, &QueryCall{Method: MethodGet, Query: query, Args: args, Dest: dest})
+}
+
+func (c *clientReplicated) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	call := &QueryCall{Method: MethodPrepareNamed, Query: query}
+	err := c.call(ctx, call)
+	return call.NamedStmt, err
+}
+
+// Close stops background checks of replicas and waits for running checks. Connections are not closed
+func (c *clientReplicated) Close() error {
+	c.mx.Lock()
+	c.stopChecks()
+	c.mx.Unlock()
+
+	c.running.Wait()
+	return nil
+}
+
+func (c *clientReplicated) EachShard(_ func(conn DB) error) error {
+	return ErrMethodNotSuppoertedInSingle
+}
+
+func (c *clientReplicated) EachShardAsync(_ func(conn DB) error, _ ...int) error {
+	return ErrMethodNotSuppoertedInSingle
+}
+
+func (c *clientReplicated) call(ctx context.Context, call *QueryCall) error {
+	if err := contextx.Validate(ctx); err != nil {
+		return err
+	}
+
+	c.route(ctx, call)
+	return c.handler(ctx, call)
+}
+
+// route sets executor of query call: transaction, replica or primary
+func (c *clientReplicated) route(ctx context.Context, call *QueryCall) {
+	if tx, ok := GetTx(ctx); ok {
+		call.Key = primaryConnectionKey
+		call.exec = tx
+		return
+	}
+
+	switch call.Method {
+	case MethodSelect, MethodGet, MethodQueryx:
+		if IsPrimary(ctx) {
+			break
+		}
+
+		if r, ok := c.selectReplica(); ok {
+			call.Key = r.key
+			call.exec = r.conn
+			return
+		}
+	}
+
+	call.Key = primaryConnectionKey
+	call.exec = c.primary
+}
+
+// selectReplica returns available replica by balance option
+func (c *clientReplicated) selectReplica() (*replica, bool) {
+	available := make([]*replica, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		c.refresh(r)
+		if r.available(c.options) {
+			available = append(available, r)
+		}
+	}
+
+	if len(available) == 0 {
+		return nil, false
+	}
+
+	if c.options.replicaBalance == ReplicaLeastLatency {
+		selected := available[0]
+		for _, r := range available[1:] {
+			if r.latency.Load() < selected.latency.Load() {
+				selected = r
+			}
+		}
+
+		return selected, true
+	}
+
+	idx := c.next.Add(1) - 1
+	return available[idx%uint64(len(available))], true
+}
+
+type replica struct {
+	key  string
+	conn *sqlx.DB
+
+	// lag, latency & checkedAt are results of the last check in nanoseconds
+	lag       atomic.Int64
+	latency   atomic.Int64
+	checkedAt atomic.Int64
+	failed    atomic.Bool
+	checking  atomic.Bool
+}
+
+func newReplica(key string, conn *sqlx.DB) *replica {
+	return &replica{
+		key:  key,
+		conn: conn,
+	}
+}
+
+// available checks if replica responded to the last check and its lag is less than max lag.
+//
+// If max lag is set, replica which lag is not checked yet is not available
+func (r *replica) available(options clientOptions) bool {
+	if r.failed.Load() {
+		return false
+	}
+
+	if options.maxReplicationLag <= 0 {
+		return true
+	}
+
+	if r.checkedAt.Load() == 0 {
+		return false
+	}
+
+	return time.Duration(r.lag.Load()) <= options.maxReplicationLag
+}
+
+// refresh starts check of replica in background if the last check is older than check interval.
+//
+// Checks are needed only to skip lagging replicas or to choose the fastest one. Checks are not started after Close
+func (c *clientReplicated) refresh(r *replica) {
+	if c.options.maxReplicationLag <= 0 && c.options.replicaBalance != ReplicaLeastLatency {
+		return
+	}
+
+	if time.Since(time.Unix(0, r.checkedAt.Load())) < c.options.replicaCheckInterval {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.checks.Err() != nil || !r.checking.CompareAndSwap(false, true) {
+		return
+	}
+
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer r.checking.Store(false)
+		r.check(c.checks, c.options.replicaCheckInterval)
+	}()
+}
+
+// check requests replication lag of replica and measures latency of the request
+func (r *replica) check(ctx context.Context, timeout time.Duration) {
+	const query = `
+SELECT CASE
+    WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+    ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lagSeconds float64
+	start := time.Now()
+	err := r.conn.QueryRowxContext(ctx, query).Scan(&lagSeconds)
+
+	r.latency.Store(int64(time.Since(start)))
+	r.lag.Store(int64(lagSeconds * float64(time.Second)))
+	r.failed.Store(err != nil)
+	r.checkedAt.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,142 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+const replicaCheckQuery = "pg_last_wal_receive_lsn"
+
+func TestReplicatedRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ClientOption
+		// check is result of replica check: lag in seconds or error
+		check       *float64
+		checkErr    error
+		ctx         func(ctx context.Context) context.Context
+		wantReplica bool
+	}{
+		{
+			name:        "replica without lag bound",
+			wantReplica: true,
+		},
+		{
+			name: "primary forced",
+			ctx:  UsePrimary,
+		},
+		{
+			name:        "replica lag is checked",
+			options:     []ClientOption{MaxReplicationLagOption(time.Second, time.Minute)},
+			check:       new(float64),
+			wantReplica: true,
+		},
+		{
+			name:    "replica is lagging",
+			options: []ClientOption{MaxReplicationLagOption(time.Second, time.Minute)},
+			check: func() *float64 {
+				lag := 5.0
+				return &lag
+			}(),
+		},
+		{
+			name:     "replica check failed",
+			options:  []ClientOption{MaxReplicationLagOption(time.Second, time.Minute)},
+			checkErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, primaryMock := newMock(t)
+			replicaConn, replicaMock := newMock(t)
+
+			if tt.check != nil {
+				replicaMock.ExpectQuery(replicaCheckQuery).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(*tt.check))
+			}
+			if tt.checkErr != nil {
+				replicaMock.ExpectQuery(replicaCheckQuery).WillReturnError(tt.checkErr)
+			}
+
+			client := NewClientReplicated(primary, []*sqlx.DB{replicaConn}, tt.options...)
+			replicated := client.(*clientReplicated)
+
+			// wait for check of replica, so route uses its result
+			replicated.selectReplica()
+			replicated.running.Wait()
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+
+			call := &QueryCall{Method: MethodSelect}
+			replicated.route(ctx, call)
+			if gotReplica := call.exec == replicaConn; gotReplica != tt.wantReplica {
+				t.Fatalf("expected replica %v, got call to %q", tt.wantReplica, call.Key)
+			}
+
+			// writes always run on primary
+			call = &QueryCall{Method: MethodExec}
+			replicated.route(ctx, call)
+			if call.exec != primary {
+				t.Fatalf("expected exec on primary, got %q", call.Key)
+			}
+
+			if err := client.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			if err := replicaMock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := primaryMock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReplicatedUncheckedReplica(t *testing.T) {
+	primary, primaryMock := newMock(t)
+	replicaConn, replicaMock := newMock(t)
+
+	client := NewClientReplicated(primary, []*sqlx.DB{replicaConn}, MaxReplicationLagOption(time.Second, time.Minute))
+
+	// check of replica is not finished, so read runs on primary
+	replicaMock.ExpectQuery(replicaCheckQuery).
+		WillDelayFor(time.Hour).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var ids []int
+	if err := client.SelectContext(context.Background(), &ids, "SELECT id FROM users"); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+
+	// Close cancels running check and waits for it
+	if err := client.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replicated := client.(*clientReplicated)
+	if replicated.replicas[0].checking.Load() {
+		t.Fatal("expected check to be finished after close")
+	}
+
+	// checks are not started after close
+	replicated.selectReplica()
+	if replicated.replicas[0].checking.Load() {
+		t.Fatal("expected no checks after close")
+	}
+
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
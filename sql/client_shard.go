@@ -5,19 +5,40 @@ import (
 	"database/sql"
 
 	"github.com/boostgo/contextx"
-	"github.com/boostgo/convert"
-	"github.com/boostgo/log"
-	"github.com/boostgo/storage"
-
+	"github.com/boostgo/errorx"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/sync/errgroup"
 )
 
 type ConnectionSelector func(ctx context.Context, connections []ShardConnect) ShardConnect
 
+// TxShardMode describes how shard client runs queries inside transaction started at one of shards
+type TxShardMode int
+
+const (
+	// TxShardStrict fails query with ErrTxShardMismatch if selector chooses shard
+	// which is not the shard of transaction from context. Helps to find queries which are routed to another shard by mistake
+	TxShardStrict TxShardMode = iota
+	// TxShardFollow runs all queries inside transaction at shard of transaction without calling selector
+	TxShardFollow
+)
+
+// TxShardOption sets how shard client checks shard of queries inside transaction. By default, TxShardStrict is used.
+//
+// Shard of transaction is known if transaction is started by transactor of Connections or set by SetTxShard.
+// In both modes queries of transaction which shard was removed still run at the shard, because shard is closed
+// only after transaction is finished. If removed shard is already closed (for example, transaction is set by SetTx
+// and does not hold the shard), queries fail with storage.ErrShardRemoved
+func TxShardOption(mode TxShardMode) ClientOption {
+	return func(options *clientOptions) {
+		options.txShard = mode
+	}
+}
+
 type clientShard struct {
 	connections *Connections
-	enableLog   bool
+	options     clientOptions
+	handler     Handler
 }
 
 // ClientShard creates DB implementation as shard client.
@@ -29,9 +50,16 @@ func ClientShard(connections *Connections, enableLog ...bool) DB {
 		enable = enableLog[0]
 	}
 
+	return NewClientShard(connections, LogOption(enable))
+}
+
+// NewClientShard creates DB implementation as shard client with provided options
+func NewClientShard(connections *Connections, options ...ClientOption) DB {
+	opts := newClientOptions(options...)
 	return &clientShard{
 		connections: connections,
-		enableLog:   enable,
+		options:     opts,
+		handler:     opts.handler(),
 	}
 }
 
@@ -40,219 +68,159 @@ func (c *clientShard) Connection() *sqlx.DB {
 }
 
 func (c *clientShard) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil, err
-	}
-	c.printLog(ctx, raw.Key(), "ExecContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.ExecContext(ctx, query, args...)
-	}
-
-	return raw.Conn().ExecContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodExec, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Result, err
 }
 
 func (c *clientShard) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil, err
-	}
-	c.printLog(ctx, raw.Key(), "QueryContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryContext(ctx, query, args...)
-	}
-
-	return raw.Conn().QueryContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodQuery, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rows, err
 }
 
 func (c *clientShard) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil, err
-	}
-	c.printLog(ctx, raw.Key(), "QueryxContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryxContext(ctx, query, args...)
-	}
-
-	return raw.Conn().QueryxContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodQueryx, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rowsx, err
 }
 
 func (c *clientShard) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil
-	}
-
-	c.printLog(ctx, raw.Key(), "QueryRowxContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryRowxContext(ctx, query, args...)
-	}
-
-	return raw.Conn().QueryRowxContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodQueryRowx, Query: query, Args: args}
+	return call.row(c.call(ctx, call))
 }
 
 func (c *clientShard) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil, err
-	}
-	c.printLog(ctx, raw.Key(), "PrepareContext", query)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.PrepareContext(ctx, query)
-	}
-
-	return raw.Conn().PrepareContext(ctx, query)
+	call := &QueryCall{Method: MethodPrepare, Query: query}
+	err := c.call(ctx, call)
+	return call.Stmt, err
 }
 
 func (c *clientShard) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return nil, err
-	}
-	c.printLog(ctx, raw.Key(), "NamedExecContext", query, arg)
+	call := &QueryCall{Method: MethodNamedExec, Query: query, Args: []any{arg}}
+	err := c.call(ctx, call)
+	return call.Result, err
+}
 
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.NamedExecContext(ctx, query, arg)
-	}
+func (c *clientShard) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.call(ctx, &QueryCall{Method: MethodSelect, Query: query, Args: args, Dest: dest})
+}
 
-	return raw.Conn().NamedExecContext(ctx, query, arg)
+func (c *clientShard) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.call(ctx, &QueryCall{Method: MethodGet, Query: query, Args: args, Dest: dest})
 }
 
-func (c *clientShard) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	if err := contextx.Validate(ctx); err != nil {
-		return err
-	}
+func (c *clientShard) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	call := &QueryCall{Method: MethodPrepareNamed, Query: query}
+	err := c.call(ctx, call)
+	return call.NamedStmt, err
+}
 
-	raw, err := c.selectConnect(ctx)
-	if err != nil {
-		return err
+// shardBegin returns provider which begins transactions at provided shard of client Connections
+func (c *clientShard) shardBegin(shard ShardConnect) TransactorConnectionProvider {
+	return shardKeyProvider{
+		connections: c.connections,
+		key:         shard.Key(),
 	}
-	c.printLog(ctx, raw.Key(), "SelectContext", query, args...)
+}
 
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.SelectContext(ctx, dest, query, args...)
-	}
+// EachShard runs provided fn function with every shard single connection
+func (c *clientShard) EachShard(fn func(conn DB) error) error {
+	return EachShard(c, fn)
+}
 
-	return raw.Conn().SelectContext(ctx, dest, query, args...)
+// EachShardAsync do the same as EachShard but in parallel every shard.
+//
+// If provide "limit", count of goroutines will be limited
+func (c *clientShard) EachShardAsync(fn func(conn DB) error, limit ...int) error {
+	return EachShardAsync(c, fn, limit...)
 }
 
-func (c *clientShard) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+// call runs query call by transaction from context or by selected shard connection.
+//
+// If shard connection is guarded by circuit breaker, call result is reported to the breaker.
+// Query is registered as in-flight, so shard removed at runtime is not closed until query is finished
+func (c *clientShard) call(ctx context.Context, call *QueryCall) error {
 	if err := contextx.Validate(ctx); err != nil {
 		return err
 	}
 
-	raw, err := c.selectConnect(ctx)
+	raw, release, err := c.acquireShard(ctx)
 	if err != nil {
 		return err
 	}
-	c.printLog(ctx, raw.Key(), "GetContext", query, args...)
+	defer release()
 
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.GetContext(ctx, dest, query, args...)
+	breaker := shardBreaker(raw)
+	if err = breaker.Allow(); err != nil {
+		return err
 	}
 
-	return raw.Conn().GetContext(ctx, dest, query, args...)
+	call.Key = raw.Key()
+	call.exec = getExecutor(ctx, raw.Conn())
+	err = c.handler(ctx, call)
+	breaker.Done(err)
+	return err
 }
 
-func (c *clientShard) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
+// acquireShard selects shard connection for query and registers query as in-flight.
+//
+// If context contain transaction with known shard, selected shard is checked by TxShardMode of client
+func (c *clientShard) acquireShard(ctx context.Context) (ShardConnect, func(), error) {
+	txShard, pinned := GetTxShard(ctx)
+	// selector never returns removed shard, so queries of transaction pinned to it go to the shard by its key
+	if pinned && (c.options.txShard == TxShardFollow || c.connections.removed(txShard)) {
+		return c.connections.acquireKey(txShard)
 	}
 
-	raw, err := c.selectConnect(ctx)
+	raw, release, err := c.connections.acquire(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	c.printLog(ctx, raw.Key(), "PrepareNamedContext", query)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.PrepareNamedContext(ctx, query)
+	if pinned && raw.Key() != txShard {
+		release()
+		return nil, nil, ErrTxShardMismatch.SetParams([]errorx.Parameter{
+			{Key: "tx_shard", Value: txShard},
+			{Key: "shard", Value: raw.Key()},
+		})
 	}
 
-	return raw.Conn().PrepareNamedContext(ctx, query)
+	return raw, release, nil
 }
 
-// EachShard runs provided fn function with every shard single connection
-func (c *clientShard) EachShard(fn func(conn DB) error) error {
-	return EachShard(c, fn)
+func (c *clientShard) sharded() bool {
+	return true
 }
 
-// EachShardAsync do the same as EachShard but in parallel every shard.
-//
-// If provide "limit", count of goroutines will be limited
-func (c *clientShard) EachShardAsync(fn func(conn DB) error, limit ...int) error {
-	return EachShardAsync(c, fn, limit...)
+// shards returns all shard connections
+func (c *clientShard) shards() []ShardConnect {
+	return c.connections.Connections()
 }
 
-func (c *clientShard) printLog(ctx context.Context, connectionKey, queryType, query string, args ...any) {
-	if !c.enableLog || storage.IsNoLog(ctx) {
-		return
-	}
-
-	convertedArgs := make([]string, 0, len(args))
-	for _, arg := range args {
-		convertedArgs = append(convertedArgs, convert.String(arg))
+// shardConn returns client of single shard which uses options & interceptors of shard client
+func (c *clientShard) shardConn(shard ShardConnect) DB {
+	return &clientShardConn{
+		clientShard: &clientShard{
+			connections: c.connections.pin(shard),
+			options:     c.options,
+			handler:     c.handler,
+		},
+		shard: shard,
 	}
-
-	log.
-		Info().
-		Ctx(ctx).
-		Str("connection_key", connectionKey).
-		Str("query_type", queryType).
-		Str("query", query).
-		Strs("args", convertedArgs).
-		Send()
-}
-
-func (c *clientShard) selectConnect(ctx context.Context) (ShardConnect, error) {
-	return c.connections.Get(ctx)
 }
 
-// EachShard runs provided fn function with every shard single connection
+// EachShard runs provided fn function with every shard single connection.
+//
+// Shard connection uses logger & interceptors of shard client
 func EachShard(conn DB, fn func(conn DB) error) (err error) {
 	shardClient, ok := conn.(*clientShard)
 	if !ok {
 		return ErrConnectionIsNotShard
 	}
 
-	for _, shard := range shardClient.connections.connections {
-		if err = fn(Client(shard.Conn())); err != nil {
+	for _, shard := range shardClient.shards() {
+		if err = fn(shardClient.shardConn(shard)); err != nil {
 			return err
 		}
 	}
@@ -274,9 +242,9 @@ func EachShardAsync(conn DB, fn func(conn DB) error, limit ...int) (err error) {
 		wg.SetLimit(limit[0])
 	}
 
-	for _, shard := range shardClient.connections.connections {
+	for _, shard := range shardClient.shards() {
 		wg.Go(func() error {
-			return fn(Client(shard.Conn()))
+			return fn(shardClient.shardConn(shard))
 		})
 	}
 
@@ -0,0 +1,124 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/storage"
+)
+
+func TestTxShardMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode []TxShardMode
+		// queryShard is shard key of query inside transaction started at shard "a"
+		queryShard string
+		// remove removes shard "a" before query
+		remove     bool
+		wantErr    error
+		wantParams []errorx.Parameter
+	}{
+		{
+			name:       "strict by default",
+			queryShard: "b",
+			wantErr:    ErrTxShardMismatch,
+			wantParams: []errorx.Parameter{
+				{Key: "tx_shard", Value: "a"},
+				{Key: "shard", Value: "b"},
+			},
+		},
+		{name: "follow", mode: []TxShardMode{TxShardFollow}, queryShard: "b"},
+		{name: "strict same shard", mode: []TxShardMode{TxShardStrict}, queryShard: "a"},
+		{
+			name:       "strict another shard",
+			mode:       []TxShardMode{TxShardStrict},
+			queryShard: "b",
+			wantErr:    ErrTxShardMismatch,
+			wantParams: []errorx.Parameter{
+				{Key: "tx_shard", Value: "a"},
+				{Key: "shard", Value: "b"},
+			},
+		},
+		// shard of transaction is closed only after transaction end, so its queries still run
+		{name: "follow removed shard", mode: []TxShardMode{TxShardFollow}, queryShard: "b", remove: true},
+		{name: "strict removed shard", queryShard: "b", remove: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connA, mockA := newMock(t)
+			connB, mockB := newMock(t)
+			connections := newConnections([]ShardConnect{
+				newShardConnect("a", nil, connA),
+				newShardConnect("b", nil, connB),
+			}, LookupSelector(map[string]string{
+				"a": "a",
+				"b": "b",
+			}))
+
+			var options []ClientOption
+			for _, mode := range tt.mode {
+				options = append(options, TxShardOption(mode))
+			}
+			client := NewClientShard(connections, options...)
+			transactor := NewTransactor(connections)
+
+			mockA.ExpectBegin()
+			ctx, err := transactor.BeginCtx(storage.WithShardKey(context.Background(), "a"))
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+
+			if tt.wantErr == nil {
+				mockA.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mockA.ExpectRollback()
+
+			// removed shard is closed after transaction end
+			removed := make(chan error, 1)
+			if tt.remove {
+				mockA.ExpectClose()
+				go func() {
+					removed <- connections.Remove(context.Background(), "a")
+				}()
+				for !connections.removed("a") {
+					time.Sleep(time.Millisecond)
+				}
+			} else {
+				removed <- nil
+			}
+
+			_, err = client.ExecContext(storage.WithShardKey(ctx, tt.queryShard), "UPDATE users SET name = 'a'")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if tt.wantParams != nil {
+				var shardErr *errorx.Error
+				if !errors.As(err, &shardErr) || !slices.Equal(shardErr.Params(), tt.wantParams) {
+					t.Fatalf("expected params %v, got %v", tt.wantParams, err)
+				}
+			}
+
+			if err = transactor.RollbackCtx(ctx); err != nil {
+				t.Fatalf("rollback: %v", err)
+			}
+
+			if err = <-removed; err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+
+			if err = mockA.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if err = mockB.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
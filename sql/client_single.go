@@ -5,15 +5,13 @@ import (
 	"database/sql"
 
 	"github.com/boostgo/contextx"
-	"github.com/boostgo/convert"
-	"github.com/boostgo/log"
-	"github.com/boostgo/storage"
 	"github.com/jmoiron/sqlx"
 )
 
 type clientSingle struct {
-	conn      *sqlx.DB
-	enableLog bool
+	conn    *sqlx.DB
+	options clientOptions
+	handler Handler
 }
 
 // Client creates DB implementation by single client
@@ -23,9 +21,16 @@ func Client(conn *sqlx.DB, enableLog ...bool) DB {
 		enable = enableLog[0]
 	}
 
+	return NewClient(conn, LogOption(enable))
+}
+
+// NewClient creates DB implementation by single client with provided options
+func NewClient(conn *sqlx.DB, options ...ClientOption) DB {
+	opts := newClientOptions(options...)
 	return &clientSingle{
-		conn:      conn,
-		enableLog: enable,
+		conn:    conn,
+		options: opts,
+		handler: opts.handler(),
 	}
 }
 
@@ -34,134 +39,57 @@ func (c *clientSingle) Connection() *sqlx.DB {
 }
 
 func (c *clientSingle) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "ExecContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.ExecContext(ctx, query, args...)
-	}
-
-	return c.conn.ExecContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodExec, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Result, err
 }
 
 func (c *clientSingle) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "QueryContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryContext(ctx, query, args...)
-	}
-
-	return c.conn.QueryContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodQuery, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rows, err
 }
 
 func (c *clientSingle) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "QueryxContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryxContext(ctx, query, args...)
-	}
-
-	return c.conn.QueryxContext(ctx, query, args...)
+	call := &QueryCall{Method: MethodQueryx, Query: query, Args: args}
+	err := c.call(ctx, call)
+	return call.Rowsx, err
 }
 
 func (c *clientSingle) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	c.printLog(ctx, "QueryRowxContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.QueryRowxContext(ctx, query, args...)
+	call := &QueryCall{
+		Method: MethodQueryRowx,
+		Query:  query,
+		Args:   args,
+		exec:   getExecutor(ctx, c.conn),
 	}
-
-	return c.conn.QueryRowxContext(ctx, query, args...)
+	return call.row(c.handler(ctx, call))
 }
 
 func (c *clientSingle) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "PrepareContext", query)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.PrepareContext(ctx, query)
-	}
-
-	return c.conn.PrepareContext(ctx, query)
+	call := &QueryCall{Method: MethodPrepare, Query: query}
+	err := c.call(ctx, call)
+	return call.Stmt, err
 }
 
 func (c *clientSingle) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "NamedExecContext", query, arg)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.NamedExecContext(ctx, query, arg)
-	}
-
-	return c.conn.NamedExecContext(ctx, query, arg)
+	call := &QueryCall{Method: MethodNamedExec, Query: query, Args: []any{arg}}
+	err := c.call(ctx, call)
+	return call.Result, err
 }
 
 func (c *clientSingle) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	if err := contextx.Validate(ctx); err != nil {
-		return err
-	}
-
-	c.printLog(ctx, "SelectContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.SelectContext(ctx, dest, query, args...)
-	}
-
-	return c.conn.SelectContext(ctx, dest, query, args...)
+	return c.call(ctx, &QueryCall{Method: MethodSelect, Query: query, Args: args, Dest: dest})
 }
 
 func (c *clientSingle) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	if err := contextx.Validate(ctx); err != nil {
-		return err
-	}
-
-	c.printLog(ctx, "GetContext", query, args...)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.GetContext(ctx, dest, query, args...)
-	}
-
-	return c.conn.GetContext(ctx, dest, query, args...)
+	return c.call(ctx, &QueryCall{Method: MethodGet, Query: query, Args: args, Dest: dest})
 }
 
 func (c *clientSingle) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
-	if err := contextx.Validate(ctx); err != nil {
-		return nil, err
-	}
-
-	c.printLog(ctx, "PrepareNamedContext", query)
-
-	tx, ok := GetTx(ctx)
-	if ok {
-		return tx.PrepareNamedContext(ctx, query)
-	}
-
-	return c.conn.PrepareNamedContext(ctx, query)
+	call := &QueryCall{Method: MethodPrepareNamed, Query: query}
+	err := c.call(ctx, call)
+	return call.NamedStmt, err
 }
 
 func (c *clientSingle) EachShard(_ func(conn DB) error) error {
@@ -172,23 +100,14 @@ func (c *clientSingle) EachShardAsync(_ func(conn DB) error, _ ...int) error {
 	return ErrMethodNotSuppoertedInSingle
 }
 
-func (c *clientSingle) printLog(ctx context.Context, queryType, query string, args ...any) {
-	if !c.enableLog || storage.IsNoLog(ctx) {
-		return
-	}
-
-	convertedArgs := make([]string, 0, len(args))
-	for _, arg := range args {
-		convertedArgs = append(convertedArgs, convert.String(arg))
+// call runs query call by transaction from context or by connection
+func (c *clientSingle) call(ctx context.Context, call *QueryCall) error {
+	if err := contextx.Validate(ctx); err != nil {
+		return err
 	}
 
-	log.
-		Info().
-		Ctx(ctx).
-		Str("queryType", queryType).
-		Str("query", query).
-		Strs("args", convertedArgs).
-		Send()
+	call.exec = getExecutor(ctx, c.conn)
+	return c.handler(ctx, call)
 }
 
 // Page returns offset & limit by pagination
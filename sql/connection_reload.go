@@ -0,0 +1,261 @@
+package sql
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/boostgo/storage"
+	"golang.org/x/sync/errgroup"
+)
+
+// Add adds shard connection. Shard key must be unique
+func (c *Connections) Add(conn ShardConnect) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	snapshot := c.load()
+	if _, ok := snapshot.drainers[conn.Key()]; ok {
+		return storage.ErrShardExists.
+			AddParam("key", conn.Key())
+	}
+
+	next := snapshot.clone()
+	next.add(c.guarded(conn))
+	c.snapshot.Store(next)
+	return nil
+}
+
+// Remove removes shard connection by key.
+//
+// New queries do not select removed shard. Connection is closed after in-flight queries & transactions are finished
+// or context is done (then storage.ErrDrainTimeout is returned, but connection is closed anyway)
+func (c *Connections) Remove(ctx context.Context, key string) error {
+	c.mx.Lock()
+	snapshot := c.load()
+	idx := snapshot.index(key)
+	if idx == -1 {
+		c.mx.Unlock()
+		return storage.ErrShardNotFound.
+			AddParam("key", key)
+	}
+
+	next := snapshot.clone()
+	next.remove(key)
+	c.snapshot.Store(next)
+	c.mx.Unlock()
+
+	return drainShard(ctx, snapshot.connections[idx], snapshot.drainers[key])
+}
+
+// Replace replaces shard connection with the same key, for example to rotate host of shard.
+//
+// New queries use new connection at once. Old connection is closed after its in-flight queries are finished
+func (c *Connections) Replace(ctx context.Context, conn ShardConnect) error {
+	c.mx.Lock()
+	snapshot := c.load()
+	idx := snapshot.index(conn.Key())
+	if idx == -1 {
+		c.mx.Unlock()
+		return storage.ErrShardNotFound.
+			AddParam("key", conn.Key())
+	}
+
+	next := snapshot.clone()
+	next.replace(idx, c.guarded(conn))
+	c.snapshot.Store(next)
+	c.mx.Unlock()
+
+	return drainShard(ctx, snapshot.connections[idx], snapshot.drainers[conn.Key()])
+}
+
+// Reload applies new shards config: connects new shards, reconnects shards which config changed
+// and removes shards which are missing in config. Not changed shards keep their connections.
+//
+// New shards are connected without blocking other changes of shards. If shards are changed concurrently,
+// connections are closed and Reload starts again with new shards list.
+// If one of shards can not be connected, no changes are applied.
+// Available only for Connections created by ConnectShards
+func (c *Connections) Reload(ctx context.Context, connectionStrings []ShardConnectString) error {
+	if c.connect == nil {
+		return storage.ErrReloadNotSupported
+	}
+
+	if err := validateConnectStrings(connectionStrings); err != nil {
+		return err
+	}
+
+	for {
+		snapshot := c.load()
+		connected, err := c.connectChanged(snapshot, connectionStrings)
+		if err != nil {
+			return err
+		}
+
+		c.mx.Lock()
+		if c.load() != snapshot {
+			c.mx.Unlock()
+			closeShards(connected)
+			continue
+		}
+
+		next := c.reloaded(snapshot, connectionStrings, connected)
+		c.snapshot.Store(next)
+		c.mx.Unlock()
+
+		// close removed & replaced shards
+		wg := errgroup.Group{}
+		for _, conn := range snapshot.connections {
+			key := conn.Key()
+			if next.drainers[key] == snapshot.drainers[key] {
+				continue
+			}
+
+			wg.Go(func() error {
+				return drainShard(ctx, conn, snapshot.drainers[key])
+			})
+		}
+
+		return wg.Wait()
+	}
+}
+
+// connectChanged connects shards which are missing in snapshot or which config is changed.
+// If one of shards can not be connected, already connected ones are closed
+func (c *Connections) connectChanged(
+	snapshot *connectionsSnapshot,
+	connectionStrings []ShardConnectString,
+) (map[string]ShardConnect, error) {
+	connected := make(map[string]ShardConnect)
+	for _, cs := range connectionStrings {
+		current, ok := snapshot.configs[cs.Key]
+		if ok && snapshot.index(cs.Key) != -1 && equalConnectStrings(current, cs) {
+			continue
+		}
+
+		conn, err := c.connect(cs)
+		if err != nil {
+			closeShards(connected)
+			return nil, err
+		}
+
+		connected[cs.Key] = conn
+	}
+
+	return connected, nil
+}
+
+// reloaded builds new shards list in order of config from snapshot & connected shards
+func (c *Connections) reloaded(
+	snapshot *connectionsSnapshot,
+	connectionStrings []ShardConnectString,
+	connected map[string]ShardConnect,
+) *connectionsSnapshot {
+	next := &connectionsSnapshot{
+		connections: make([]ShardConnect, 0, len(connectionStrings)),
+		drainers:    make(map[string]*storage.Drainer, len(connectionStrings)),
+		configs:     make(map[string]ShardConnectString, len(connectionStrings)),
+		removed:     maps.Clone(snapshot.removed),
+	}
+	for _, conn := range snapshot.connections {
+		next.removed[conn.Key()] = removedShard{
+			conn:    conn,
+			drainer: snapshot.drainers[conn.Key()],
+		}
+	}
+	for _, cs := range connectionStrings {
+		next.configs[cs.Key] = cs
+		if conn, ok := connected[cs.Key]; ok {
+			next.add(c.guarded(conn))
+			continue
+		}
+
+		next.connections = append(next.connections, snapshot.connections[snapshot.index(cs.Key)])
+		next.drainers[cs.Key] = snapshot.drainers[cs.Key]
+		delete(next.removed, cs.Key)
+	}
+
+	return next
+}
+
+// closeShards closes shards which were connected but not applied
+func closeShards(connections map[string]ShardConnect) {
+	for _, conn := range connections {
+		_ = conn.Close()
+	}
+}
+
+// guarded wraps shard connection by guard of Connections if it exist
+func (c *Connections) guarded(conn ShardConnect) ShardConnect {
+	if c.guard == nil {
+		return conn
+	}
+
+	return c.guard(conn)
+}
+
+func (s *connectionsSnapshot) clone() *connectionsSnapshot {
+	return &connectionsSnapshot{
+		connections: slices.Clone(s.connections),
+		drainers:    maps.Clone(s.drainers),
+		configs:     maps.Clone(s.configs),
+		removed:     maps.Clone(s.removed),
+	}
+}
+
+func (s *connectionsSnapshot) index(key string) int {
+	return slices.IndexFunc(s.connections, func(conn ShardConnect) bool {
+		return conn.Key() == key
+	})
+}
+
+func (s *connectionsSnapshot) isRemoved(key string) bool {
+	_, ok := s.removed[key]
+	return ok
+}
+
+func (s *connectionsSnapshot) add(conn ShardConnect) {
+	s.connections = append(s.connections, conn)
+	s.drainers[conn.Key()] = &storage.Drainer{}
+	delete(s.removed, conn.Key())
+}
+
+func (s *connectionsSnapshot) remove(key string) {
+	if idx := s.index(key); idx != -1 {
+		s.removed[key] = removedShard{
+			conn:    s.connections[idx],
+			drainer: s.drainers[key],
+		}
+	}
+
+	s.connections = slices.DeleteFunc(s.connections, func(conn ShardConnect) bool {
+		return conn.Key() == key
+	})
+	delete(s.drainers, key)
+	delete(s.configs, key)
+}
+
+// replace sets connection to the same position, so selectors depending on shards order keep routing
+func (s *connectionsSnapshot) replace(idx int, conn ShardConnect) {
+	s.connections[idx] = conn
+	s.drainers[conn.Key()] = &storage.Drainer{}
+	delete(s.configs, conn.Key())
+}
+
+// drainShard waits for in-flight queries of removed shard and closes it
+func drainShard(ctx context.Context, conn ShardConnect, drainer *storage.Drainer) error {
+	drainErr := drainer.Drain(ctx)
+	if err := conn.Close(); err != nil {
+		return ErrCloseShard.
+			SetError(err).
+			AddParam("key", conn.Key())
+	}
+
+	return drainErr
+}
+
+func equalConnectStrings(a, b ShardConnectString) bool {
+	return a.Key == b.Key &&
+		a.ConnectionString == b.ConnectionString &&
+		slices.Equal(a.Conditions, b.Conditions)
+}
@@ -0,0 +1,297 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+// testShard is shard connection without database which records if it is closed
+type testShard struct {
+	key    string
+	host   string
+	closed atomic.Bool
+}
+
+func (s *testShard) Key() string          { return s.key }
+func (s *testShard) Conditions() []string { return []string{} }
+func (s *testShard) Conn() *sqlx.DB       { return nil }
+
+func (s *testShard) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+// newReloadConnections creates Connections of shards a & b which connect shards by configs
+func newReloadConnections(t *testing.T, errConnect error) *Connections {
+	t.Helper()
+
+	configs := []ShardConnectString{
+		{Key: "a", ConnectionString: "host=a"},
+		{Key: "b", ConnectionString: "host=b"},
+	}
+
+	connect := func(cs ShardConnectString) (ShardConnect, error) {
+		if cs.ConnectionString == "host=fail" {
+			return nil, errConnect
+		}
+
+		return &testShard{key: cs.Key, host: cs.ConnectionString}, nil
+	}
+
+	shards := make([]ShardConnect, len(configs))
+	for idx, cs := range configs {
+		shards[idx], _ = connect(cs)
+	}
+
+	connections := newConnections(shards, LookupSelector(nil))
+	connections.connect = connect
+	for _, cs := range configs {
+		connections.load().configs[cs.Key] = cs
+	}
+
+	return connections
+}
+
+func TestReload(t *testing.T) {
+	errConnect := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		configs []ShardConnectString
+		wantErr error
+		// wantShards are connection strings of shards after reload in order of shards
+		wantShards []string
+		// wantKept are keys of shards which keep their connection
+		wantKept []string
+		// wantClosed are keys of shards which old connection is closed
+		wantClosed []string
+	}{
+		{
+			name: "not changed",
+			configs: []ShardConnectString{
+				{Key: "a", ConnectionString: "host=a"},
+				{Key: "b", ConnectionString: "host=b"},
+			},
+			wantShards: []string{"host=a", "host=b"},
+			wantKept:   []string{"a", "b"},
+		},
+		{
+			name: "changed, added & removed",
+			configs: []ShardConnectString{
+				{Key: "b", ConnectionString: "host=b2"},
+				{Key: "c", ConnectionString: "host=c"},
+			},
+			wantShards: []string{"host=b2", "host=c"},
+			wantClosed: []string{"a", "b"},
+		},
+		{
+			name: "conditions changed",
+			configs: []ShardConnectString{
+				{Key: "a", ConnectionString: "host=a", Conditions: []string{"region = eu"}},
+				{Key: "b", ConnectionString: "host=b"},
+			},
+			wantShards: []string{"host=a", "host=b"},
+			wantKept:   []string{"b"},
+			wantClosed: []string{"a"},
+		},
+		{
+			name: "connect failed",
+			configs: []ShardConnectString{
+				{Key: "a", ConnectionString: "host=a2"},
+				{Key: "b", ConnectionString: "host=fail"},
+			},
+			wantErr:    errConnect,
+			wantShards: []string{"host=a", "host=b"},
+			wantKept:   []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connections := newReloadConnections(t, errConnect)
+			before := make(map[string]*testShard)
+			for _, conn := range connections.Connections() {
+				before[conn.Key()] = conn.(*testShard)
+			}
+
+			err := connections.Reload(context.Background(), tt.configs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			shards := make([]string, 0)
+			after := make(map[string]ShardConnect)
+			for _, conn := range connections.Connections() {
+				shards = append(shards, conn.(*testShard).host)
+				after[conn.Key()] = conn
+			}
+			if !slices.Equal(shards, tt.wantShards) {
+				t.Fatalf("expected shards %v, got %v", tt.wantShards, shards)
+			}
+
+			for key, shard := range before {
+				kept := after[key] == ShardConnect(shard)
+				if kept != slices.Contains(tt.wantKept, key) {
+					t.Fatalf("shard %s: expected kept %v, got %v", key, !kept, kept)
+				}
+
+				if closed := shard.closed.Load(); closed != slices.Contains(tt.wantClosed, key) {
+					t.Fatalf("shard %s: expected closed %v, got %v", key, !closed, closed)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadConnectsWithoutLock(t *testing.T) {
+	connections := newReloadConnections(t, nil)
+
+	// the first connect of shard c waits until shard d is added
+	var (
+		dialed  []*testShard
+		connect = connections.connect
+		dialing = make(chan struct{})
+		added   = make(chan struct{})
+	)
+	connections.connect = func(cs ShardConnectString) (ShardConnect, error) {
+		if len(dialed) == 0 {
+			close(dialing)
+			<-added
+		}
+
+		conn, err := connect(cs)
+		dialed = append(dialed, conn.(*testShard))
+		return conn, err
+	}
+
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- connections.Reload(context.Background(), []ShardConnectString{
+			{Key: "a", ConnectionString: "host=a"},
+			{Key: "c", ConnectionString: "host=c"},
+		})
+	}()
+
+	// shards can be changed while reload connects new shards
+	<-dialing
+	if err := connections.Add(&testShard{key: "d"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	close(added)
+
+	if err := <-reloaded; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	// reload started again with new shards list, so connection of the first try is closed
+	if len(dialed) != 2 || !dialed[0].closed.Load() || dialed[1].closed.Load() {
+		t.Fatalf("expected the first connection to be closed and the second one to be used")
+	}
+
+	keys := make([]string, 0)
+	for _, conn := range connections.Connections() {
+		keys = append(keys, conn.Key())
+	}
+	if want := []string{"a", "c"}; !slices.Equal(keys, want) {
+		t.Fatalf("expected shards %v, got %v", want, keys)
+	}
+}
+
+func TestReloadNotSupported(t *testing.T) {
+	connections := newConnections([]ShardConnect{&testShard{key: "a"}}, LookupSelector(nil))
+	err := connections.Reload(context.Background(), []ShardConnectString{{Key: "a", ConnectionString: "host=a"}})
+	if !errors.Is(err, storage.ErrReloadNotSupported) {
+		t.Fatalf("expected reload not supported, got %v", err)
+	}
+}
+
+func TestRemoveWaitsForTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   TxShardMode
+		expect func(mock sqlmock.Sqlmock)
+		end    func(ctx context.Context, transactor storage.Transactor) error
+	}{
+		{
+			name: "commit",
+			mode: TxShardFollow,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectCommit()
+			},
+			end: func(ctx context.Context, transactor storage.Transactor) error {
+				return transactor.CommitCtx(ctx)
+			},
+		},
+		{
+			name: "rollback",
+			mode: TxShardStrict,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectRollback()
+			},
+			end: func(ctx context.Context, transactor storage.Transactor) error {
+				return transactor.RollbackCtx(ctx)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			connections := newConnections([]ShardConnect{newShardConnect("a", nil, conn)}, LookupSelector(map[string]string{
+				"a": "a",
+			}))
+			transactor := NewTransactor(connections)
+
+			mock.ExpectBegin()
+			ctx, err := transactor.BeginCtx(storage.WithShardKey(context.Background(), "a"))
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+
+			removed := make(chan error, 1)
+			go func() {
+				removed <- connections.Remove(context.Background(), "a")
+			}()
+
+			select {
+			case err = <-removed:
+				t.Fatalf("expected shard to be removed after transaction end, got %v", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			// query of transaction pinned to removed shard runs until transaction end
+			client := NewClientShard(connections, TxShardOption(tt.mode))
+			mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+			if _, err = client.ExecContext(ctx, "UPDATE users SET name = 'a'"); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+
+			tt.expect(mock)
+			mock.ExpectClose()
+			if err = tt.end(ctx, transactor); err != nil {
+				t.Fatalf("end transaction: %v", err)
+			}
+
+			if err = <-removed; err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+
+			// shard is closed, so context of finished transaction can not use it anymore
+			if _, err = client.ExecContext(ctx, "UPDATE users SET name = 'a'"); !errors.Is(err, storage.ErrShardRemoved) {
+				t.Fatalf("expected shard removed, got %v", err)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
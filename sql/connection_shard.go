@@ -3,6 +3,8 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/boostgo/errorx"
@@ -14,24 +16,29 @@ import (
 type ShardConnectString struct {
 	Key              string
 	ConnectionString string
+	// Conditions are used by ConditionSelector to route queries to the shard
+	Conditions []string
 }
 
 // ShardConnect contain connection & it's key for shard client
 type ShardConnect interface {
 	Key() string
+	Conditions() []string
 	Conn() *sqlx.DB
 	Close() error
 }
 
 type shardConnect struct {
-	key  string
-	conn *sqlx.DB
+	key        string
+	conditions []string
+	conn       *sqlx.DB
 }
 
-func newShardConnect(key string, conn *sqlx.DB) ShardConnect {
+func newShardConnect(key string, conditions []string, conn *sqlx.DB) ShardConnect {
 	return &shardConnect{
-		key:  key,
-		conn: conn,
+		key:        key,
+		conditions: conditions,
+		conn:       conn,
 	}
 }
 
@@ -40,6 +47,15 @@ func (conn *shardConnect) Key() string {
 	return conn.key
 }
 
+// Conditions returns routing conditions of connection
+func (conn *shardConnect) Conditions() []string {
+	if conn.conditions == nil {
+		return []string{}
+	}
+
+	return conn.conditions
+}
+
 // Conn return single connection
 func (conn *shardConnect) Conn() *sqlx.DB {
 	return conn.conn
@@ -57,39 +73,66 @@ func ConnectShards(
 	timeout time.Duration,
 	options ...func(connection *sqlx.DB),
 ) (*Connections, error) {
-	// validate for connection key unique and for empty
-	// also, validate for empty connection string
+	if err := validateConnectStrings(connectionStrings); err != nil {
+		return nil, err
+	}
+
+	connect := func(cs ShardConnectString) (ShardConnect, error) {
+		connection, err := Connect(driverName, cs.ConnectionString, timeout, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		return newShardConnect(cs.Key, cs.Conditions, connection), nil
+	}
+
+	// connect every shard
+	connections := make([]ShardConnect, len(connectionStrings))
+	for idx, cs := range connectionStrings {
+		connection, err := connect(cs)
+		if err != nil {
+			return nil, err
+		}
+
+		connections[idx] = connection
+	}
+
+	conns := newConnections(connections, selector)
+	conns.connect = connect
+	for _, cs := range connectionStrings {
+		conns.load().configs[cs.Key] = cs
+	}
+
+	return conns, nil
+}
+
+// validateConnectStrings validates for connection key unique and for empty.
+// Also, validates for empty connection string & shard conditions
+func validateConnectStrings(connectionStrings []ShardConnectString) error {
 	keys := make(map[string]struct{}, len(connectionStrings))
 	for _, cs := range connectionStrings {
 		if cs.Key == "" {
-			return nil, errorx.New("Connection key is empty")
+			return errorx.New("Connection key is empty")
 		}
 
 		if cs.ConnectionString == "" {
-			return nil, ErrConnectionStringEmpty.
+			return ErrConnectionStringEmpty.
 				AddParam("key", cs.Key)
 		}
 
 		if _, ok := keys[cs.Key]; ok {
-			return nil, ErrConnectionStringDuplicate.
+			return ErrConnectionStringDuplicate.
 				AddParam("key", cs.Key)
 		}
 
-		keys[cs.Key] = struct{}{}
-	}
-
-	// connect every shard
-	connections := make([]ShardConnect, len(connectionStrings))
-	for idx, cs := range connectionStrings {
-		connection, err := Connect(driverName, cs.ConnectionString, timeout, options...)
-		if err != nil {
-			return nil, err
+		if err := storage.ValidateConditions(cs.Conditions); err != nil {
+			return err
 		}
 
-		connections[idx] = newShardConnect(cs.Key, connection)
+		keys[cs.Key] = struct{}{}
 	}
 
-	return newConnections(connections, selector), nil
+	return nil
 }
 
 // MustConnectShards calls ConnectShards and if error catch throws panic
@@ -108,39 +151,187 @@ func MustConnectShards(
 	return connections
 }
 
-// Connections contain all connections for shard client and selector for choosing connection
+// Connections contain all connections for shard client and selector for choosing connection.
+//
+// Shards can be added, removed or replaced at runtime (see Add, Remove, Replace & Reload).
+// Every query selects shard from snapshot of shards, so selector always sees consistent list
 type Connections struct {
+	// mx serializes changes of shards
+	mx       sync.Mutex
+	snapshot atomic.Pointer[connectionsSnapshot]
+	selector ConnectionSelector
+	// connect creates shard connection from config. Set only if Connections created by ConnectShards
+	connect func(cs ShardConnectString) (ShardConnect, error)
+	// guard wraps every added shard connection, for example by circuit breaker
+	guard func(conn ShardConnect) ShardConnect
+}
+
+// connectionsSnapshot is immutable list of shards. Every change of shards creates new snapshot
+type connectionsSnapshot struct {
 	connections []ShardConnect
-	selector    ConnectionSelector
+	// drainers count in-flight queries of every shard
+	drainers map[string]*storage.Drainer
+	// configs of shards created from ShardConnectString, used by Reload
+	configs map[string]ShardConnectString
+	// removed are shards removed at runtime. Queries of in-flight transactions pinned to them still run
+	// until transactions are finished, other queries get storage.ErrShardRemoved
+	removed map[string]removedShard
+}
+
+// removedShard is shard removed at runtime which may be still drained
+type removedShard struct {
+	conn    ShardConnect
+	drainer *storage.Drainer
 }
 
 func newConnections(connections []ShardConnect, selector ConnectionSelector) *Connections {
-	return &Connections{
-		connections: connections,
-		selector:    selector,
+	drainers := make(map[string]*storage.Drainer, len(connections))
+	for _, conn := range connections {
+		drainers[conn.Key()] = &storage.Drainer{}
+	}
+
+	c := &Connections{
+		selector: selector,
 	}
+	c.snapshot.Store(&connectionsSnapshot{
+		connections: connections,
+		drainers:    drainers,
+		configs:     make(map[string]ShardConnectString),
+		removed:     make(map[string]removedShard),
+	})
+	return c
+}
+
+func (c *Connections) load() *connectionsSnapshot {
+	return c.snapshot.Load()
 }
 
 // Get returns shard connect by using selector
 func (c *Connections) Get(ctx context.Context) (ShardConnect, error) {
+	return c.selectFrom(ctx, c.load())
+}
+
+func (c *Connections) selectFrom(ctx context.Context, snapshot *connectionsSnapshot) (ShardConnect, error) {
 	// get shard by provided selector
-	conn := c.selector(ctx, c.connections)
+	ctx, selectionErr := storage.TrackSelection(ctx)
+	conn := c.selector(ctx, snapshot.connections)
 	if conn == nil {
+		if err := selectionErr(); err != nil {
+			return nil, err
+		}
+
 		return nil, storage.ErrConnNotSelected
 	}
 
 	return conn, nil
 }
 
-// Connections return all shard connections
+// acquire selects shard connect and registers in-flight query of it.
+//
+// Returned function must be called after query is finished, so removed shard can be closed.
+// If selected shard is removed concurrently, selection is repeated with new snapshot
+func (c *Connections) acquire(ctx context.Context) (ShardConnect, func(), error) {
+	for {
+		snapshot := c.load()
+		conn, err := c.selectFrom(ctx, snapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		drainer, ok := snapshot.drainers[conn.Key()]
+		if !ok {
+			// selector returned connection which is not part of the shards
+			return conn, func() {}, nil
+		}
+
+		if drainer.Acquire() {
+			return conn, drainer.Release, nil
+		}
+
+		if c.load() == snapshot {
+			return nil, nil, storage.ErrShardRemoved.
+				AddParam("key", conn.Key())
+		}
+	}
+}
+
+// acquireKey registers in-flight query of shard with provided key.
+//
+// Query of removed shard joins its in-flight queries, so transaction pinned to the shard can run queries
+// until it is finished. Returns storage.ErrShardRemoved if removed shard is already drained
+// and ErrTxShardMismatch if there is no such shard, for example if transaction is started at another Connections
+func (c *Connections) acquireKey(key string) (ShardConnect, func(), error) {
+	snapshot := c.load()
+	idx := snapshot.index(key)
+	if idx == -1 {
+		if removed, ok := snapshot.removed[key]; ok {
+			if !removed.drainer.Join() {
+				return nil, nil, storage.ErrShardRemoved.
+					AddParam("key", key)
+			}
+
+			return removed.conn, removed.drainer.Release, nil
+		}
+
+		return nil, nil, ErrTxShardMismatch.
+			AddParam("tx_shard", key)
+	}
+
+	drainer := snapshot.drainers[key]
+	if !drainer.Acquire() {
+		return nil, nil, storage.ErrShardRemoved.
+			AddParam("key", key)
+	}
+
+	return snapshot.connections[idx], drainer.Release, nil
+}
+
+// removed checks if shard with provided key was removed at runtime and not added again
+func (c *Connections) removed(key string) bool {
+	return c.load().isRemoved(key)
+}
+
+// pin creates Connections of single shard which shares in-flight queries counter with c
+func (c *Connections) pin(shard ShardConnect) *Connections {
+	pinned := newConnections([]ShardConnect{shard}, pinnedSelector)
+	if drainer, ok := c.load().drainers[shard.Key()]; ok {
+		pinned.load().drainers[shard.Key()] = drainer
+	}
+
+	return pinned
+}
+
+// guardWith wraps every shard connection by guard. Shards added later are wrapped too
+func (c *Connections) guardWith(guard func(conn ShardConnect) ShardConnect) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	next := c.load().clone()
+	for idx, conn := range next.connections {
+		next.connections[idx] = guard(conn)
+	}
+
+	if prev := c.guard; prev != nil {
+		c.guard = func(conn ShardConnect) ShardConnect {
+			return guard(prev(conn))
+		}
+	} else {
+		c.guard = guard
+	}
+
+	c.snapshot.Store(next)
+}
+
+// Connections return all shard connections. Returned slice must not be modified
 func (c *Connections) Connections() []ShardConnect {
-	return c.connections
+	return c.load().connections
 }
 
 // RawConnections returns all connections as []*sqlx.DB
 func (c *Connections) RawConnections() []*sqlx.DB {
-	connections := make([]*sqlx.DB, len(c.connections))
-	for idx, conn := range c.connections {
+	shards := c.Connections()
+	connections := make([]*sqlx.DB, len(shards))
+	for idx, conn := range shards {
 		connections[idx] = conn.Conn()
 	}
 	return connections
@@ -150,7 +341,7 @@ func (c *Connections) RawConnections() []*sqlx.DB {
 func (c *Connections) Close() error {
 	wg := errgroup.Group{}
 
-	for _, conn := range c.connections {
+	for _, conn := range c.Connections() {
 		wg.Go(conn.Close)
 	}
 
@@ -159,11 +350,48 @@ func (c *Connections) Close() error {
 
 // BeginTxx method for TransactorConnectionProvider implementation by choosing connection by selector
 func (c *Connections) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
-	// begin transaction at selected shard
-	conn, err := c.Get(ctx)
+	tx, conn, err := c.beginShardTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return conn.Conn().BeginTxx(ctx, opts)
+	// end of transaction is unknown, so it does not hold removed shard
+	conn.release()
+	return tx, nil
+}
+
+// beginShardTx begins transaction at selected shard and returns connection of the shard.
+//
+// Transactor sets key of the shard to context with transaction, so shard client can check queries inside transaction.
+// Transaction is registered as in-flight query of the shard until release of returned connection is called
+func (c *Connections) beginShardTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, txConn, error) {
+	// begin transaction at selected shard
+	conn, release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, txConn{}, err
+	}
+
+	return beginAcquired(ctx, conn, release, opts)
+}
+
+// beginAcquired begins transaction at shard acquired by Connections. Release is called if transaction is not started
+func beginAcquired(ctx context.Context, conn ShardConnect, release func(), opts *sql.TxOptions) (*sqlx.Tx, txConn, error) {
+	breaker := shardBreaker(conn)
+	if err := breaker.Allow(); err != nil {
+		release()
+		return nil, txConn{}, err
+	}
+
+	tx, err := conn.Conn().BeginTxx(ctx, opts)
+	breaker.Done(err)
+	if err != nil {
+		release()
+		return nil, txConn{}, err
+	}
+
+	return tx, txConn{
+		db:      conn.Conn(),
+		shard:   conn.Key(),
+		release: release,
+	}, nil
 }
@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/boostgo/storage"
+)
+
+type decisionStore struct {
+	db DB
+}
+
+// NewDecisionStore creates storage.DecisionStore based on "storage_tx_decisions" table.
+//
+// Table is created by MigrateStorage. Decisions are saved out of transaction from context,
+// because they are saved while transactions of context are prepared.
+// If shard client is provided, decision is saved to the shard chosen by selector and looked up at all shards
+func NewDecisionStore(db DB) storage.DecisionStore {
+	return &decisionStore{
+		db: db,
+	}
+}
+
+func (store *decisionStore) SaveCommit(ctx context.Context, globalID string) error {
+	const query = `
+INSERT INTO storage_tx_decisions (global_id)
+VALUES ($1)
+ON CONFLICT (global_id) DO NOTHING`
+
+	_, err := store.db.ExecContext(withoutTx(ctx), query, globalID)
+	return err
+}
+
+func (store *decisionStore) IsCommitted(ctx context.Context, globalID string) (bool, error) {
+	const query = `SELECT EXISTS(SELECT 1 FROM storage_tx_decisions WHERE global_id = $1)`
+
+	var committed bool
+	err := eachConn(store.db, func(conn DB) error {
+		var exists bool
+		if err := conn.GetContext(withoutTx(ctx), &exists, query, globalID); err != nil {
+			return err
+		}
+
+		committed = committed || exists
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return committed, nil
+}
+
+func (store *decisionStore) Forget(ctx context.Context, globalID string) error {
+	const query = `DELETE FROM storage_tx_decisions WHERE global_id = $1`
+
+	return eachConn(store.db, func(conn DB) error {
+		_, err := conn.ExecContext(withoutTx(ctx), query, globalID)
+		return err
+	})
+}
+
+// withoutTx returns context which queries run out of transaction from provided context
+func withoutTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transactionKey, nil)
+}
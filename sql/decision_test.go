@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+)
+
+func TestDecisionStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		run    func(ctx context.Context, store storage.DecisionStore) (bool, error)
+		want   bool
+	}{
+		{
+			name: "save commit",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO storage_tx_decisions").
+					WithArgs("g1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(ctx context.Context, store storage.DecisionStore) (bool, error) {
+				return false, store.SaveCommit(ctx, "g1")
+			},
+		},
+		{
+			name: "committed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM storage_tx_decisions").
+					WithArgs("g1").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			run: func(ctx context.Context, store storage.DecisionStore) (bool, error) {
+				return store.IsCommitted(ctx, "g1")
+			},
+			want: true,
+		},
+		{
+			name: "not committed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM storage_tx_decisions").
+					WithArgs("g1").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			run: func(ctx context.Context, store storage.DecisionStore) (bool, error) {
+				return store.IsCommitted(ctx, "g1")
+			},
+		},
+		{
+			name: "forget",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM storage_tx_decisions").
+					WithArgs("g1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(ctx context.Context, store storage.DecisionStore) (bool, error) {
+				return false, store.Forget(ctx, "g1")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			store := NewDecisionStore(NewClient(conn))
+
+			// decision is saved while transaction of context is prepared and closed, so store must not use it
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+			tx, err := conn.Beginx()
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+			_ = tx.Rollback()
+
+			tt.expect(mock)
+			got, err := tt.run(SetTx(context.Background(), tx), store)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -9,8 +9,13 @@ var (
 	ErrConnectionStringEmpty     = errorx.New("sql.connection_string_empty")
 	ErrConnectionStringDuplicate = errorx.New("sql.connection_string_duplicate")
 	ErrConnectionIsNotShard      = errorx.New("sql.connection_is_not_shard")
+	ErrCloseShard                = errorx.New("sql.close_shard")
+	ErrTxShardMismatch           = errorx.New("sql.tx_shard_mismatch")
 
 	ErrMethodNotSuppoertedInSingle = errorx.New("sql.method_not_suppoerted_in_single")
+	ErrUnknownMethod               = errorx.New("sql.unknown_method")
+	ErrScatterTransaction          = errorx.New("sql.scatter_transaction")
+	ErrShardTxNested               = errorx.New("sql.shard_tx_nested")
 
 	ErrMigrateOpenConn          = errorx.New("migrate.open_conn")
 	ErrMigrateGetDriver         = errorx.New("migrate.get_driver")
@@ -18,9 +23,16 @@ var (
 	ErrMigrateReadMigrationsDir = errorx.New("migrate.read_migrations_dir")
 	ErrMigrateUp                = errorx.New("migrate.up")
 
-	ErrTransactorBegin    = errorx.New("transactor.begin")
-	ErrTransactorCommit   = errorx.New("transactor.commit")
-	ErrTransactorRollback = errorx.New("transactor.rollback")
+	ErrTransactorBegin     = errorx.New("transactor.begin")
+	ErrTransactorCommit    = errorx.New("transactor.commit")
+	ErrTransactorRollback  = errorx.New("transactor.rollback")
+	ErrTransactorSavepoint = errorx.New("transactor.savepoint")
+	ErrTransactorPrepare   = errorx.New("transactor.prepare")
+	ErrPrepareNotSupported = errorx.New("transactor.prepare_not_supported")
+	ErrRecoverAgeInvalid   = errorx.New("transactor.recover_age_invalid")
+
+	ErrOutboxEnqueue = errorx.New("outbox.enqueue")
+	ErrOutboxRelay   = errorx.New("outbox.relay")
 )
 
 type openConnectContext struct {
@@ -0,0 +1,27 @@
+package sql
+
+import (
+	"github.com/boostgo/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+// DBProbe creates health probe which pings provided connection
+func DBProbe(name string, conn *sqlx.DB) storage.Probe {
+	return storage.Probe{
+		Name: name,
+		Ping: conn.PingContext,
+	}
+}
+
+// Probes creates health probe for every shard connection. Probes are named as "sql/<shard key>"
+//
+// Register it by storage.Health.RegisterSource to follow shards added or removed at runtime
+func (c *Connections) Probes() []storage.Probe {
+	connections := c.Connections()
+	probes := make([]storage.Probe, 0, len(connections))
+	for _, conn := range connections {
+		probes = append(probes, DBProbe("sql/"+conn.Key(), conn.Conn()))
+	}
+
+	return probes
+}
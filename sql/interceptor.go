@@ -0,0 +1,266 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Names of DB methods passed to interceptors as QueryCall.Method
+const (
+	MethodExec         = "ExecContext"
+	MethodQuery        = "QueryContext"
+	MethodQueryx       = "QueryxContext"
+	MethodQueryRowx    = "QueryRowxContext"
+	MethodPrepare      = "PrepareContext"
+	MethodNamedExec    = "NamedExecContext"
+	MethodSelect       = "SelectContext"
+	MethodGet          = "GetContext"
+	MethodPrepareNamed = "PrepareNamedContext"
+)
+
+// QueryCall describes call of DB method.
+//
+// Interceptor can change query, arguments or destination before calling next handler
+// and read results after it
+type QueryCall struct {
+	// Method is name of called DB method
+	Method string
+	// Key is key of shard connection. Empty for single client and for DB wrapped by Intercept
+	Key   string
+	Query string
+	// Args are arguments of query. For NamedExecContext it contain single named argument
+	Args []any
+	// Dest is destination of SelectContext and GetContext
+	Dest any
+
+	// Results of the call. Only result of called method is set
+	Result    sql.Result
+	Rows      *sql.Rows
+	Rowsx     *sqlx.Rows
+	Row       *sqlx.Row
+	Stmt      *sql.Stmt
+	NamedStmt *sqlx.NamedStmt
+
+	exec queryExecutor
+}
+
+// rowsAffected returns count of affected or selected rows by finished call
+func (call *QueryCall) rowsAffected(err error) int64 {
+	switch call.Method {
+	case MethodExec, MethodNamedExec:
+		return rowsAffected(call.Result)
+	case MethodSelect:
+		return rowsSelected(call.Dest)
+	case MethodGet:
+		if err == nil {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// row returns row of finished QueryRowxContext call. Returned row is never nil:
+// if call failed before row was received or error of the row was replaced by interceptor, row carries the error
+func (call *QueryCall) row(err error) *sqlx.Row {
+	if err == nil && call.Row != nil {
+		return call.Row
+	}
+
+	// release connection of the row which result is replaced by error
+	if call.Row != nil && call.Row.Err() == nil {
+		_ = call.Row.Scan()
+	}
+
+	if err == nil {
+		err = sql.ErrNoRows
+	}
+
+	return errRow(err)
+}
+
+// Handler runs query call
+type Handler func(ctx context.Context, call *QueryCall) error
+
+// Interceptor wraps query call. Interceptor must call next handler to run the query
+type Interceptor func(ctx context.Context, call *QueryCall, next Handler) error
+
+// InterceptorOption adds interceptors to client.
+//
+// Interceptors are called in provided order. Shard client calls interceptors after connection is selected,
+// so QueryCall.Key is set
+func InterceptorOption(interceptors ...Interceptor) ClientOption {
+	return func(options *clientOptions) {
+		options.interceptors = append(options.interceptors, interceptors...)
+	}
+}
+
+// chain wraps handler by interceptors, so the first interceptor is called first
+func chain(interceptors []Interceptor, handler Handler) Handler {
+	for idx := len(interceptors) - 1; idx >= 0; idx-- {
+		interceptor, next := interceptors[idx], handler
+		handler = func(ctx context.Context, call *QueryCall) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return handler
+}
+
+// queryExecutor is common part of DB, connection and transaction
+type queryExecutor interface {
+	sqlx.ExecerContext
+	sqlx.QueryerContext
+	sqlx.PreparerContext
+	GetContext
+	NamedExecContext
+	SelectContext
+	PrepareContext
+}
+
+// executeCall runs call by executor of the call and sets result
+func executeCall(ctx context.Context, call *QueryCall) (err error) {
+	switch call.Method {
+	case MethodExec:
+		call.Result, err = call.exec.ExecContext(ctx, call.Query, call.Args...)
+	case MethodQuery:
+		call.Rows, err = call.exec.QueryContext(ctx, call.Query, call.Args...)
+	case MethodQueryx:
+		call.Rowsx, err = call.exec.QueryxContext(ctx, call.Query, call.Args...)
+	case MethodQueryRowx:
+		call.Row = call.exec.QueryRowxContext(ctx, call.Query, call.Args...)
+		if call.Row != nil {
+			err = call.Row.Err()
+		}
+	case MethodPrepare:
+		call.Stmt, err = call.exec.PrepareContext(ctx, call.Query)
+	case MethodNamedExec:
+		var arg any
+		if len(call.Args) > 0 {
+			arg = call.Args[0]
+		}
+		call.Result, err = call.exec.NamedExecContext(ctx, call.Query, arg)
+	case MethodSelect:
+		err = call.exec.SelectContext(ctx, call.Dest, call.Query, call.Args...)
+	case MethodGet:
+		err = call.exec.GetContext(ctx, call.Dest, call.Query, call.Args...)
+	case MethodPrepareNamed:
+		call.NamedStmt, err = call.exec.PrepareNamedContext(ctx, call.Query)
+	default:
+		return ErrUnknownMethod.AddParam("method", call.Method)
+	}
+
+	return err
+}
+
+type interceptedDB struct {
+	db           DB
+	interceptors []Interceptor
+	handler      Handler
+}
+
+// Intercept wraps any DB implementation by interceptors.
+//
+// Every shard connection provided by EachShard & EachShardAsync is wrapped by the same interceptors
+func Intercept(db DB, interceptors ...Interceptor) DB {
+	return &interceptedDB{
+		db:           db,
+		interceptors: interceptors,
+		handler:      chain(interceptors, executeCall),
+	}
+}
+
+func (i *interceptedDB) Connection() *sqlx.DB {
+	return i.db.Connection()
+}
+
+func (i *interceptedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	call := &QueryCall{Method: MethodExec, Query: query, Args: args}
+	err := i.call(ctx, call)
+	return call.Result, err
+}
+
+func (i *interceptedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	call := &QueryCall{Method: MethodQuery, Query: query, Args: args}
+	err := i.call(ctx, call)
+	return call.Rows, err
+}
+
+func (i *interceptedDB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	call := &QueryCall{Method: MethodQueryx, Query: query, Args: args}
+	err := i.call(ctx, call)
+	return call.Rowsx, err
+}
+
+func (i *interceptedDB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	call := &QueryCall{Method: MethodQueryRowx, Query: query, Args: args}
+	return call.row(i.call(ctx, call))
+}
+
+func (i *interceptedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	call := &QueryCall{Method: MethodPrepare, Query: query}
+	err := i.call(ctx, call)
+	return call.Stmt, err
+}
+
+func (i *interceptedDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	call := &QueryCall{Method: MethodNamedExec, Query: query, Args: []any{arg}}
+	err := i.call(ctx, call)
+	return call.Result, err
+}
+
+func (i *interceptedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return i.call(ctx, &QueryCall{Method: MethodSelect, Query: query, Args: args, Dest: dest})
+}
+
+func (i *interceptedDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return i.call(ctx, &QueryCall{Method: MethodGet, Query: query, Args: args, Dest: dest})
+}
+
+func (i *interceptedDB) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	call := &QueryCall{Method: MethodPrepareNamed, Query: query}
+	err := i.call(ctx, call)
+	return call.NamedStmt, err
+}
+
+func (i *interceptedDB) EachShard(fn func(conn DB) error) error {
+	return i.db.EachShard(func(conn DB) error {
+		return fn(Intercept(conn, i.interceptors...))
+	})
+}
+
+func (i *interceptedDB) EachShardAsync(fn func(conn DB) error, limit ...int) error {
+	return i.db.EachShardAsync(func(conn DB) error {
+		return fn(Intercept(conn, i.interceptors...))
+	}, limit...)
+}
+
+// sharded checks if wrapped DB consists of shards
+func (i *interceptedDB) sharded() bool {
+	iterator, ok := i.db.(shardIterator)
+	return ok && iterator.sharded()
+}
+
+func (i *interceptedDB) shards() []ShardConnect {
+	if !i.sharded() {
+		return nil
+	}
+
+	return i.db.(shardIterator).shards()
+}
+
+// shardConn returns client of single shard of wrapped DB wrapped by the same interceptors
+func (i *interceptedDB) shardConn(shard ShardConnect) DB {
+	return Intercept(i.db.(shardIterator).shardConn(shard), i.interceptors...)
+}
+
+func (i *interceptedDB) shardBegin(shard ShardConnect) TransactorConnectionProvider {
+	return i.db.(shardIterator).shardBegin(shard)
+}
+
+func (i *interceptedDB) call(ctx context.Context, call *QueryCall) error {
+	call.exec = i.db
+	return i.handler(ctx, call)
+}
@@ -0,0 +1,167 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	conn, mock := newMock(t)
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call *QueryCall, next Handler) error {
+			calls = append(calls, name+":before")
+			err := next(ctx, call)
+			calls = append(calls, name+":after")
+			return err
+		}
+	}
+
+	client := NewClient(conn, InterceptorOption(record("first"), record("second")))
+	if _, err := client.ExecContext(context.Background(), "UPDATE users SET name = 'a'"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	want := []string{"first:before", "second:before", "second:after", "first:after"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestInterceptorRewritesQuery(t *testing.T) {
+	conn, mock := newMock(t)
+	mock.ExpectExec("UPDATE users_v2").
+		WithArgs("b").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rewrite := func(ctx context.Context, call *QueryCall, next Handler) error {
+		call.Query = "UPDATE users_v2 SET name = $1"
+		call.Args = []any{"b"}
+		return next(ctx, call)
+	}
+
+	db := Intercept(NewClient(conn), rewrite)
+	if _, err := db.ExecContext(context.Background(), "UPDATE users SET name = $1", "a"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInterceptedQueryRowx(t *testing.T) {
+	errRejected := errors.New("rejected")
+
+	tests := []struct {
+		name    string
+		query   bool
+		rows    []int
+		icpt    Interceptor
+		wantErr error
+	}{
+		{
+			name:  "row",
+			query: true,
+			rows:  []int{1},
+		},
+		{
+			name:    "no rows",
+			query:   true,
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name: "interceptor rejects call",
+			icpt: func(ctx context.Context, call *QueryCall, next Handler) error {
+				return errRejected
+			},
+			wantErr: errRejected,
+		},
+		{
+			name:  "interceptor replaces result by error",
+			query: true,
+			rows:  []int{1},
+			icpt: func(ctx context.Context, call *QueryCall, next Handler) error {
+				_ = next(ctx, call)
+				return errRejected
+			},
+			wantErr: errRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+
+			var interceptors []Interceptor
+			if tt.icpt != nil {
+				interceptors = append(interceptors, tt.icpt)
+			}
+
+			for name, db := range map[string]DB{
+				"client":      NewClient(conn, InterceptorOption(interceptors...)),
+				"intercepted": Intercept(NewClient(conn), interceptors...),
+			} {
+				if tt.query {
+					rows := sqlmock.NewRows([]string{"id"})
+					for _, id := range tt.rows {
+						rows.AddRow(id)
+					}
+					mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows)
+				}
+
+				row := db.QueryRowxContext(context.Background(), "SELECT id FROM users")
+				if row == nil {
+					t.Fatalf("%s: expected row, got nil", name)
+				}
+
+				var id int
+				err := row.Scan(&id)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s: expected error %v, got %v", name, tt.wantErr, err)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestErrRow(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "no rows", err: sql.ErrNoRows},
+		{name: "interceptor error", err: errors.New("query rejected")},
+		{name: "another error", err: errors.New("connection lost")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := errRow(tt.err).Scan(); !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// rows of all errors are received from the same connection pool
+	db := errRowDB
+	_ = errRow(sql.ErrNoRows).Err()
+	if errRowDB != db {
+		t.Fatal("expected error rows to share connection pool")
+	}
+
+	if open := db.Stats().OpenConnections; open != 0 {
+		t.Fatalf("expected no open connections, got %d", open)
+	}
+}
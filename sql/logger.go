@@ -1,7 +1,82 @@
 package sql
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/boostgo/convert"
+	"github.com/boostgo/log"
+)
+
+// QueryLog describes finished query
+type QueryLog struct {
+	// Key is key of shard connection. Empty for single client
+	Key       string
+	QueryType string
+	Query     string
+	Args      []any
+	Duration  time.Duration
+	// RowsAffected is count of rows affected by exec queries or count of selected rows by select queries.
+	// For other query types it is 0
+	RowsAffected int64
+	Err          error
+}
 
 type Logger interface {
 	Print(ctx context.Context, key, queryType, query string, args []any)
 }
+
+// QueryLogger prints queries called by client with their results.
+//
+// Log is called after query is finished
+type QueryLogger interface {
+	Log(ctx context.Context, entry QueryLog)
+}
+
+// loggerAdapter prints queries by Logger, which does not receive query results
+type loggerAdapter struct {
+	logger Logger
+}
+
+func (adapter loggerAdapter) Log(ctx context.Context, entry QueryLog) {
+	adapter.logger.Print(ctx, entry.Key, entry.QueryType, entry.Query, entry.Args)
+}
+
+type defaultLogger struct{}
+
+// DefaultLogger returns logger which prints queries by global logger.
+//
+// Successful queries and queries which found nothing (see NotFound) are printed with "info" level,
+// failed queries are printed with "error" level
+func DefaultLogger() QueryLogger {
+	return defaultLogger{}
+}
+
+func (defaultLogger) Log(ctx context.Context, entry QueryLog) {
+	convertedArgs := make([]string, 0, len(entry.Args))
+	for _, arg := range entry.Args {
+		convertedArgs = append(convertedArgs, convert.String(arg))
+	}
+
+	event := log.Info()
+	switch {
+	case entry.Err == nil:
+	case NotFound(entry.Err):
+		event = event.Err(entry.Err)
+	default:
+		event = log.Error().Err(entry.Err)
+	}
+
+	if entry.Key != "" {
+		event = event.Str("connection_key", entry.Key)
+	}
+
+	event.
+		Ctx(ctx).
+		Str("query_type", entry.QueryType).
+		Str("query", entry.Query).
+		Strs("args", convertedArgs).
+		Duration("duration", entry.Duration).
+		Int64("rows_affected", entry.RowsAffected).
+		Send()
+}
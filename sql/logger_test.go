@@ -0,0 +1,118 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+)
+
+// printLogger is Logger which records printed queries
+type printLogger struct {
+	queries []string
+}
+
+func (logger *printLogger) Print(_ context.Context, _, queryType, query string, _ []any) {
+	logger.queries = append(logger.queries, queryType+" "+query)
+}
+
+// entryLogger is QueryLogger which records query entries
+type entryLogger struct {
+	entries []QueryLog
+}
+
+func (logger *entryLogger) Log(_ context.Context, entry QueryLog) {
+	logger.entries = append(logger.entries, entry)
+}
+
+func TestClientLoggers(t *testing.T) {
+	execErr := errors.New("syntax error")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		execErr  error
+		wantLogs int
+	}{
+		{name: "successful query", ctx: context.Background(), wantLogs: 1},
+		{name: "failed query", ctx: context.Background(), execErr: execErr, wantLogs: 1},
+		{name: "no log context", ctx: storage.NoLog(context.Background()), wantLogs: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			printer := &printLogger{}
+			entries := &entryLogger{}
+
+			for range 2 {
+				exec := mock.ExpectExec("DELETE FROM users")
+				if tt.execErr != nil {
+					exec.WillReturnError(tt.execErr)
+				} else {
+					exec.WillReturnResult(sqlmock.NewResult(0, 3))
+				}
+			}
+
+			_, _ = NewClient(conn, LoggerOption(printer)).ExecContext(tt.ctx, "DELETE FROM users")
+			_, _ = NewClient(conn, QueryLoggerOption(entries)).ExecContext(tt.ctx, "DELETE FROM users")
+
+			if len(printer.queries) != tt.wantLogs || len(entries.entries) != tt.wantLogs {
+				t.Fatalf("expected %d logs, got %d printed and %d entries", tt.wantLogs, len(printer.queries), len(entries.entries))
+			}
+
+			if tt.wantLogs == 0 {
+				return
+			}
+
+			if printer.queries[0] != "ExecContext DELETE FROM users" {
+				t.Fatalf("unexpected printed query: %s", printer.queries[0])
+			}
+
+			entry := entries.entries[0]
+			if !errors.Is(entry.Err, tt.execErr) {
+				t.Fatalf("expected error %v, got %v", tt.execErr, entry.Err)
+			}
+			if tt.execErr == nil && entry.RowsAffected != 3 {
+				t.Fatalf("expected 3 rows affected, got %d", entry.RowsAffected)
+			}
+		})
+	}
+}
+
+func TestDefaultLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry QueryLog
+	}{
+		{
+			name: "successful query",
+			entry: QueryLog{
+				Key:          "a",
+				QueryType:    "ExecContext",
+				Query:        "DELETE FROM users WHERE id = $1",
+				Args:         []any{1},
+				Duration:     time.Millisecond,
+				RowsAffected: 1,
+			},
+		},
+		{
+			name:  "not found",
+			entry: QueryLog{QueryType: "GetContext", Query: "SELECT 1", Err: sql.ErrNoRows},
+		},
+		{
+			name:  "failed query",
+			entry: QueryLog{QueryType: "ExecContext", Query: "DELETE", Err: errors.New("syntax error")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultLogger().Log(context.Background(), tt.entry)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "storage_sql"
+
+type statsCollector struct {
+	connections *Connections
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewStatsCollector creates prometheus collector of connection pool statistics (sql.DBStats)
+// of every shard connection. Metrics are labeled by shard key
+func NewStatsCollector(connections *Connections) prometheus.Collector {
+	labels := []string{"shard"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+	}
+
+	return &statsCollector{
+		connections: connections,
+
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "The number of established connections both in use and idle."),
+		inUse:             desc("in_use_connections", "The number of connections currently in use."),
+		idle:              desc("idle_connections", "The number of idle connections."),
+		waitCount:         desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns."),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime."),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, conn := range c.connections.Connections() {
+		// shard without connection pool has no statistics
+		db := conn.Conn()
+		if db == nil {
+			continue
+		}
+
+		key := conn.Key()
+		stats := db.Stats()
+
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), key)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections), key)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse), key)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle), key)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount), key)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), key)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), key)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), key)
+		ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), key)
+	}
+}
+
+// QueryMetrics is prometheus collector of query durations.
+//
+// Durations are observed by interceptor returned by Interceptor method
+// and labeled by method, shard key (empty for single client) and status ("ok" or "error")
+type QueryMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewQueryMetrics creates query durations collector. If buckets are not provided, prometheus.DefBuckets are used
+func NewQueryMetrics(buckets ...float64) *QueryMetrics {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	return &QueryMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "query_duration_seconds",
+			Help:      "Duration of queries by DB method and shard.",
+			Buckets:   buckets,
+		}, []string{"method", "shard", "status"}),
+	}
+}
+
+func (m *QueryMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+}
+
+func (m *QueryMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+}
+
+// Interceptor returns interceptor which observes query durations.
+//
+// Can be provided to client by InterceptorOption or to Intercept
+func (m *QueryMetrics) Interceptor() Interceptor {
+	return func(ctx context.Context, call *QueryCall, next Handler) error {
+		start := time.Now()
+		err := next(ctx, call)
+
+		status := "ok"
+		if err != nil && !NotFound(err) {
+			status = "error"
+		}
+
+		m.duration.
+			WithLabelValues(call.Method, call.Key, status).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
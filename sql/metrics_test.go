@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStatsCollector(t *testing.T) {
+	conn, _ := newMock(t)
+
+	tests := []struct {
+		name        string
+		connections []ShardConnect
+		// wantShards is count of shards which statistics are collected
+		wantShards int
+	}{
+		{
+			name:        "all shards",
+			connections: []ShardConnect{newShardConnect("a", nil, conn), newShardConnect("b", nil, conn)},
+			wantShards:  2,
+		},
+		{
+			name:        "shard without connection pool",
+			connections: []ShardConnect{newShardConnect("a", nil, conn), newShardConnect("b", nil, nil)},
+			wantShards:  1,
+		},
+		{
+			name: "no shards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := NewStatsCollector(newConnections(tt.connections, LookupSelector(nil)))
+
+			ch := make(chan prometheus.Metric, 64)
+			collector.Collect(ch)
+			close(ch)
+
+			const metricsPerShard = 9
+			if len(ch) != tt.wantShards*metricsPerShard {
+				t.Fatalf("expected %d metrics, got %d", tt.wantShards*metricsPerShard, len(ch))
+			}
+		})
+	}
+}
@@ -2,17 +2,26 @@ package sql
 
 import (
 	"context"
+	"embed"
 	"errors"
 
 	"github.com/boostgo/log"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
+// storageMigrationsTable is table of applied storage migrations.
+// Separate table is used to not conflict with migrations of the project
+const storageMigrationsTable = "storage_schema_migrations"
+
+//go:embed migrations/*.sql
+var storageMigrations embed.FS
+
 // Migrate runs migration by provided connection & database name.
 //
 // Use by default ./migrations directory in the root of project.
@@ -82,3 +91,37 @@ func BackgroundMigrate(ctx context.Context, conn *sqlx.DB, databaseName string)
 func AsyncMigrate(ctx context.Context, conn *sqlx.DB, databaseName string) {
 	go BackgroundMigrate(ctx, conn, databaseName)
 }
+
+// MigrateStorage creates tables used by package tools (saga store, outbox, two-phase commit decisions).
+//
+// Applied migrations are tracked in separate "storage_schema_migrations" table
+func MigrateStorage(ctx context.Context, conn *sqlx.DB) error {
+	nativeConn, err := conn.Conn(ctx)
+	if err != nil {
+		return ErrMigrateOpenConn.SetError(err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, nativeConn, &postgres.Config{
+		MigrationsTable: storageMigrationsTable,
+	})
+	if err != nil {
+		return ErrMigrateGetDriver.SetError(err)
+	}
+
+	source, err := iofs.New(storageMigrations, "migrations")
+	if err != nil {
+		return ErrMigrateReadMigrationsDir.SetError(err)
+	}
+
+	migrator, err := migrate.NewWithInstance("iofs", source, "storage", driver)
+	if err != nil {
+		return ErrMigrateReadMigrationsDir.SetError(err)
+	}
+	defer migrator.Close()
+
+	if err = migrator.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return ErrMigrateUp.SetError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS storage_sagas
+(
+    id         TEXT PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    status     TEXT        NOT NULL,
+    step       INTEGER     NOT NULL DEFAULT 0,
+    payload    BYTEA,
+    error      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS storage_sagas_pending_idx
+    ON storage_sagas (name, status)
+    WHERE status IN ('running', 'compensating', 'failed');
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS storage_outbox
+(
+    id              BIGSERIAL PRIMARY KEY,
+    topic           TEXT        NOT NULL,
+    payload         BYTEA,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ,
+    failed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS storage_outbox_pending_idx
+    ON storage_outbox (next_attempt_at, id)
+    WHERE sent_at IS NULL AND failed_at IS NULL;
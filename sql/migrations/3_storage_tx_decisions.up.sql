@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS storage_tx_decisions
+(
+    global_id  TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
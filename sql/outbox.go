@@ -0,0 +1,252 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/boostgo/storage"
+	"github.com/lib/pq"
+)
+
+// OutboxMessage is message stored in outbox table
+type OutboxMessage struct {
+	ID        int64     `db:"id"`
+	Topic     string    `db:"topic"`
+	Payload   []byte    `db:"payload"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Outbox writes messages to "storage_outbox" table (transactional outbox pattern).
+//
+// Table is created by MigrateStorage
+type Outbox struct {
+	db DB
+}
+
+// NewOutbox creates Outbox. DB can be single or shard client
+func NewOutbox(db DB) *Outbox {
+	return &Outbox{
+		db: db,
+	}
+}
+
+// Enqueue writes message to outbox table.
+//
+// If context contain transaction, message is written inside it and will be committed atomically with business data.
+// Messages are published later by OutboxRelay
+func (o *Outbox) Enqueue(ctx context.Context, topic string, payload []byte) error {
+	const query = `INSERT INTO storage_outbox (topic, payload) VALUES ($1, $2)`
+
+	if _, err := o.db.ExecContext(ctx, query, topic, payload); err != nil {
+		return ErrOutboxEnqueue.
+			SetError(err).
+			AddParam("topic", topic)
+	}
+
+	return nil
+}
+
+// OutboxPublisher publishes batch of outbox messages to broker.
+//
+// If error is returned, all messages of the batch are scheduled for retry
+type OutboxPublisher func(ctx context.Context, messages []OutboxMessage) error
+
+// OutboxOption sets relay settings
+type OutboxOption func(relay *OutboxRelay)
+
+// OutboxBatchSizeOption sets max count of messages published at once
+func OutboxBatchSizeOption(batchSize int) OutboxOption {
+	return func(relay *OutboxRelay) {
+		if batchSize <= 0 {
+			return
+		}
+
+		relay.batchSize = batchSize
+	}
+}
+
+// OutboxIntervalOption sets delay between polls when there are no messages to publish
+func OutboxIntervalOption(interval time.Duration) OutboxOption {
+	return func(relay *OutboxRelay) {
+		if interval <= 0 {
+			return
+		}
+
+		relay.interval = interval
+	}
+}
+
+// OutboxRetryOption sets retry policy of failed messages.
+//
+// Codes of the policy are ignored. After MaxAttempts message is marked as failed and not published anymore
+func OutboxRetryOption(policy RetryPolicy) OutboxOption {
+	return func(relay *OutboxRelay) {
+		relay.retry = policy
+	}
+}
+
+// OutboxRelay polls outbox table and publishes messages by provided publisher
+type OutboxRelay struct {
+	db        DB
+	publisher OutboxPublisher
+	batchSize int
+	interval  time.Duration
+	retry     RetryPolicy
+}
+
+// NewOutboxRelay creates relay of outbox messages.
+//
+// If DB is shard client, every shard is polled
+func NewOutboxRelay(db DB, publisher OutboxPublisher, options ...OutboxOption) *OutboxRelay {
+	const (
+		defaultBatchSize   = 100
+		defaultInterval    = time.Second
+		defaultMaxAttempts = 10
+		defaultMinBackoff  = time.Second
+		defaultMaxBackoff  = time.Minute * 5
+	)
+
+	relay := &OutboxRelay{
+		db:        db,
+		publisher: publisher,
+		batchSize: defaultBatchSize,
+		interval:  defaultInterval,
+		retry: RetryPolicy{
+			MaxAttempts: defaultMaxAttempts,
+			MinBackoff:  defaultMinBackoff,
+			MaxBackoff:  defaultMaxBackoff,
+		},
+	}
+
+	for _, option := range options {
+		option(relay)
+	}
+
+	return relay
+}
+
+// Run polls outbox until context is done.
+//
+// Errors are logged and polling continues
+func (r *OutboxRelay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		published, err := r.Relay(ctx)
+		if err != nil {
+			log.
+				Error().
+				Ctx(ctx).
+				Err(err).
+				Msg("Relay outbox messages")
+		}
+
+		// poll again at once if batch was full
+		delay := r.interval
+		if err == nil && published >= r.batchSize {
+			delay = 0
+		}
+		timer.Reset(delay)
+	}
+}
+
+// Relay publishes one batch of pending messages from every shard.
+//
+// Returns max count of messages handled on one shard
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	var handled int
+	err := eachConn(r.db, func(conn DB) error {
+		count, err := r.relayConn(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		handled = max(handled, count)
+		return nil
+	})
+
+	return handled, err
+}
+
+// relayConn locks batch of pending messages, publishes them and marks as sent or schedules retry
+func (r *OutboxRelay) relayConn(ctx context.Context, conn DB) (count int, err error) {
+	const selectQuery = `
+SELECT id, topic, payload, attempts, created_at
+FROM storage_outbox
+WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`
+
+	// relay transaction must not depend on transaction of the caller
+	ctx = storage.SetPropagation(ctx, storage.PropagationRequiresNew)
+	err = Atomic(ctx, conn.Connection(), func(ctx context.Context) error {
+		messages := make([]OutboxMessage, 0, r.batchSize)
+		if err = conn.SelectContext(ctx, &messages, selectQuery, r.batchSize); err != nil {
+			return err
+		}
+
+		count = len(messages)
+		if count == 0 {
+			return nil
+		}
+
+		if publishErr := r.publisher(ctx, messages); publishErr != nil {
+			return r.retryLater(ctx, conn, messages, publishErr)
+		}
+
+		return r.markSent(ctx, conn, messages)
+	})
+	if err != nil {
+		return count, ErrOutboxRelay.SetError(err)
+	}
+
+	return count, nil
+}
+
+func (r *OutboxRelay) markSent(ctx context.Context, conn DB, messages []OutboxMessage) error {
+	const query = `UPDATE storage_outbox SET sent_at = now() WHERE id = ANY($1)`
+
+	_, err := conn.ExecContext(ctx, query, pq.Array(outboxIDs(messages)))
+	return err
+}
+
+// retryLater schedules next attempt of every message or marks message as failed if attempts are over
+func (r *OutboxRelay) retryLater(ctx context.Context, conn DB, messages []OutboxMessage, publishErr error) error {
+	const query = `
+UPDATE storage_outbox
+SET attempts        = attempts + 1,
+    last_error      = $2,
+    next_attempt_at = now() + $3 * INTERVAL '1 millisecond',
+    failed_at       = CASE WHEN $4 THEN now() END
+WHERE id = $1`
+
+	for _, message := range messages {
+		attempts := message.Attempts + 1
+		failed := r.retry.MaxAttempts > 0 && attempts >= r.retry.MaxAttempts
+		backoff := r.retry.Backoff(attempts).Milliseconds()
+
+		if _, err := conn.ExecContext(ctx, query, message.ID, publishErr.Error(), backoff, failed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func outboxIDs(messages []OutboxMessage) []int64 {
+	ids := make([]int64, len(messages))
+	for idx, message := range messages {
+		ids[idx] = message.ID
+	}
+	return ids
+}
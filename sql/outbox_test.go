@@ -0,0 +1,154 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOutboxEnqueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		execErr error
+		wantErr error
+	}{
+		{name: "enqueued"},
+		{name: "failed", execErr: errors.New("connection lost"), wantErr: ErrOutboxEnqueue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			outbox := NewOutbox(NewClient(conn))
+
+			exec := mock.ExpectExec("INSERT INTO storage_outbox").WithArgs("orders", []byte("payload"))
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			if err := outbox.Enqueue(context.Background(), "orders", []byte("payload")); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	columns := []string{"id", "topic", "payload", "attempts", "created_at"}
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishErr := errors.New("broker unavailable")
+
+	tests := []struct {
+		name       string
+		rows       *sqlmock.Rows
+		publishErr error
+		expect     func(mock sqlmock.Sqlmock)
+		wantCount  int
+		wantIDs    []int64
+	}{
+		{
+			name: "no messages",
+			rows: sqlmock.NewRows(columns),
+		},
+		{
+			name: "published",
+			rows: sqlmock.NewRows(columns).
+				AddRow(1, "orders", []byte("a"), 0, createdAt).
+				AddRow(2, "orders", []byte("b"), 0, createdAt),
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE storage_outbox SET sent_at").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			wantCount: 2,
+			wantIDs:   []int64{1, 2},
+		},
+		{
+			name: "publish failed",
+			rows: sqlmock.NewRows(columns).
+				AddRow(1, "orders", []byte("a"), 0, createdAt).
+				AddRow(2, "orders", []byte("b"), 2, createdAt),
+			publishErr: publishErr,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE storage_outbox").
+					WithArgs(int64(1), publishErr.Error(), sqlmock.AnyArg(), false).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				// the last attempt marks message as failed
+				mock.ExpectExec("UPDATE storage_outbox").
+					WithArgs(int64(2), publishErr.Error(), sqlmock.AnyArg(), true).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantCount: 2,
+			wantIDs:   []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+
+			var published []int64
+			relay := NewOutboxRelay(NewClient(conn), func(ctx context.Context, messages []OutboxMessage) error {
+				for _, message := range messages {
+					published = append(published, message.ID)
+				}
+
+				return tt.publishErr
+			}, OutboxBatchSizeOption(10), OutboxRetryOption(RetryPolicy{MaxAttempts: 3}))
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("FROM storage_outbox").WithArgs(10).WillReturnRows(tt.rows)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+			mock.ExpectCommit()
+
+			count, err := relay.Relay(context.Background())
+			if err != nil {
+				t.Fatalf("relay: %v", err)
+			}
+
+			if count != tt.wantCount {
+				t.Fatalf("expected %d messages, got %d", tt.wantCount, count)
+			}
+
+			if !slices.Equal(published, tt.wantIDs) {
+				t.Fatalf("expected published %v, got %v", tt.wantIDs, published)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOutboxRelaySelectFailed(t *testing.T) {
+	conn, mock := newMock(t)
+	relay := NewOutboxRelay(NewClient(conn), func(ctx context.Context, messages []OutboxMessage) error {
+		t.Fatal("publisher must not be called")
+		return nil
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM storage_outbox").WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	if _, err := relay.Relay(context.Background()); !errors.Is(err, ErrOutboxRelay) {
+		t.Fatalf("expected relay error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
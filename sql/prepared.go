@@ -0,0 +1,271 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/boostgo/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// preparedPrefix is prefix of global identifiers of transactions prepared by transactor
+const preparedPrefix = "storage_"
+
+// PrepareCtx prepares transaction from context by PREPARE TRANSACTION (first phase of two-phase commit).
+//
+// Requires max_prepared_transactions > 0 in PostgreSQL settings and transactor created with *sqlx.DB or Connections,
+// because prepared transaction is finished by another connection of the same pool.
+// If transaction is joined or nested, nothing is done and transaction is committed by its owner
+func (st *sqlTransactor) PrepareCtx(ctx context.Context) error {
+	return prepareScope(ctx)
+}
+
+// CommitPreparedCtx commits transaction prepared by PrepareCtx (second phase of two-phase commit).
+//
+// If transaction was not prepared, works as CommitCtx
+func (st *sqlTransactor) CommitPreparedCtx(ctx context.Context) error {
+	return commitPreparedScope(ctx)
+}
+
+// RollbackPreparedCtx rolls back transaction prepared by PrepareCtx.
+//
+// If transaction was not prepared, works as RollbackCtx
+func (st *sqlTransactor) RollbackPreparedCtx(ctx context.Context) error {
+	return rollbackPreparedScope(ctx)
+}
+
+// prepareScope prepares transaction from context if scope owns it
+func prepareScope(ctx context.Context) error {
+	tx, ok := GetTx(ctx)
+	if !ok {
+		return nil
+	}
+
+	scope, ok := getScope(ctx, tx)
+	if !ok || !scope.owner {
+		return nil
+	}
+
+	if scope.state.rollbackOnly.Load() {
+		return storage.ErrTransactionRollbackOnly
+	}
+
+	if scope.db == nil {
+		return ErrTransactorPrepare.SetError(ErrPrepareNotSupported)
+	}
+
+	gid, err := newPreparedID(ctx)
+	if err != nil {
+		return ErrTransactorPrepare.SetError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "PREPARE TRANSACTION "+pq.QuoteLiteral(gid)); err != nil {
+		return ErrTransactorPrepare.SetError(err)
+	}
+
+	// after PREPARE TRANSACTION session of transaction is not in transaction anymore,
+	// so transaction object is only closed. Driver may drop its connection, prepared transaction is kept by database
+	_ = tx.Rollback()
+	scope.gid = gid
+	return nil
+}
+
+// commitPreparedScope commits prepared transaction from context or commits scope if transaction was not prepared
+func commitPreparedScope(ctx context.Context) error {
+	scope, ok := getPrepared(ctx)
+	if !ok {
+		return commitScope(ctx)
+	}
+	defer scope.finish()
+
+	if err := finishPrepared(ctx, scope, "COMMIT PREPARED"); err != nil {
+		return ErrTransactorCommit.
+			SetError(err).
+			AddParam("gid", scope.gid)
+	}
+
+	scope.hooks.RunCommit()
+	return nil
+}
+
+// rollbackPreparedScope rolls back prepared transaction from context or rolls back scope if transaction was not prepared
+func rollbackPreparedScope(ctx context.Context) error {
+	scope, ok := getPrepared(ctx)
+	if !ok {
+		return rollbackScope(ctx)
+	}
+	defer scope.finish()
+
+	err := finishPrepared(ctx, scope, "ROLLBACK PREPARED")
+	scope.hooks.RunRollback()
+	if err != nil {
+		return ErrTransactorRollback.
+			SetError(err).
+			AddParam("gid", scope.gid)
+	}
+
+	return nil
+}
+
+// getPrepared returns scope of transaction from context if transaction was prepared
+func getPrepared(ctx context.Context) (*txScope, bool) {
+	tx, ok := GetTx(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	scope, ok := getScope(ctx, tx)
+	if !ok || scope.gid == "" {
+		return nil, false
+	}
+
+	return scope, true
+}
+
+// finishPrepared runs COMMIT PREPARED or ROLLBACK PREPARED for transaction of scope
+// by dedicated connection of transaction pool
+func finishPrepared(ctx context.Context, scope *txScope, command string) error {
+	conn, err := scope.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, command+" "+pq.QuoteLiteral(scope.gid))
+	return err
+}
+
+// newPreparedID returns global identifier for transaction prepared by transactor.
+//
+// If context contains global transaction (see storage.BeginGlobalTx), identifier is derived from it
+// as "storage_<global id>_<branch>", so recovery can find commit decision of the transaction.
+// Otherwise, random identifier is used
+func newPreparedID(ctx context.Context) (string, error) {
+	if globalID, branch, ok := storage.GlobalTxBranch(ctx); ok {
+		return preparedPrefix + globalID + "_" + branch, nil
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return preparedPrefix + hex.EncodeToString(id), nil
+}
+
+// preparedGlobalID returns identifier of global transaction which prepared transaction belongs to
+func preparedGlobalID(gid string) string {
+	globalID, _, _ := strings.Cut(strings.TrimPrefix(gid, preparedPrefix), "_")
+	return globalID
+}
+
+// PreparedTransaction is transaction prepared by two-phase commit but not committed or rolled back yet
+type PreparedTransaction struct {
+	GID      string    `db:"gid"`
+	Prepared time.Time `db:"prepared"`
+	Owner    string    `db:"owner"`
+	Database string    `db:"database"`
+}
+
+// PreparedTransactions returns transactions of current database prepared by transactor
+func PreparedTransactions(ctx context.Context, conn *sqlx.DB) ([]PreparedTransaction, error) {
+	return preparedTransactions(ctx, conn, 0)
+}
+
+// preparedTransactions returns transactions prepared by transactor earlier than provided age by database clock
+func preparedTransactions(ctx context.Context, conn *sqlx.DB, olderThan time.Duration) ([]PreparedTransaction, error) {
+	const query = `
+SELECT gid, prepared, owner, database
+FROM pg_prepared_xacts
+WHERE database = current_database() AND gid LIKE $1 AND prepared <= now() - make_interval(secs => $2)
+ORDER BY prepared`
+
+	prepared := make([]PreparedTransaction, 0)
+	if err := conn.SelectContext(ctx, &prepared, query, preparedPrefix+"%", olderThan.Seconds()); err != nil {
+		return nil, err
+	}
+
+	return prepared, nil
+}
+
+// CommitPrepared commits prepared transaction by its global identifier
+func CommitPrepared(ctx context.Context, conn *sqlx.DB, gid string) error {
+	if _, err := conn.ExecContext(ctx, "COMMIT PREPARED "+pq.QuoteLiteral(gid)); err != nil {
+		return ErrTransactorCommit.
+			SetError(err).
+			AddParam("gid", gid)
+	}
+
+	return nil
+}
+
+// RollbackPrepared rolls back prepared transaction by its global identifier
+func RollbackPrepared(ctx context.Context, conn *sqlx.DB, gid string) error {
+	if _, err := conn.ExecContext(ctx, "ROLLBACK PREPARED "+pq.QuoteLiteral(gid)); err != nil {
+		return ErrTransactorRollback.
+			SetError(err).
+			AddParam("gid", gid)
+	}
+
+	return nil
+}
+
+// RecoverPrepared resolves orphaned prepared transactions which are older than provided age.
+//
+// Such transactions stay after coordinator crash between prepare & commit phases and hold their locks.
+// Transaction is committed if commit decision of its global transaction is saved in provided store
+// and rolled back otherwise. Decisions are kept, because other databases may still have prepared transactions
+// of the same global transaction.
+// Age is checked by database clock and must be positive and longer than the longest time between prepare
+// and saving decision by coordinators, otherwise transactions which are being committed right now may be rolled back.
+// Returns global identifiers of resolved transactions
+func RecoverPrepared(
+	ctx context.Context,
+	conn *sqlx.DB,
+	decisions storage.DecisionStore,
+	olderThan time.Duration,
+) ([]string, error) {
+	if decisions == nil {
+		return nil, storage.ErrDecisionStoreMissing
+	}
+
+	if olderThan <= 0 {
+		return nil, ErrRecoverAgeInvalid.
+			AddParam("older_than", olderThan.String())
+	}
+
+	prepared, err := preparedTransactions(ctx, conn, olderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(prepared))
+	for _, tx := range prepared {
+		if !strings.HasPrefix(tx.GID, preparedPrefix) {
+			continue
+		}
+
+		committed, err := decisions.IsCommitted(ctx, preparedGlobalID(tx.GID))
+		if err != nil {
+			return resolved, err
+		}
+
+		if committed {
+			err = CommitPrepared(ctx, conn, tx.GID)
+		} else {
+			err = RollbackPrepared(ctx, conn, tx.GID)
+		}
+
+		if err != nil {
+			return resolved, err
+		}
+
+		resolved = append(resolved, tx.GID)
+	}
+
+	return resolved, nil
+}
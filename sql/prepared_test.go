@@ -0,0 +1,277 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestTwoPhaseCommit(t *testing.T) {
+	tests := []struct {
+		name      string
+		finish    func(transactor storage.Preparer, ctx context.Context) error
+		command   string
+		finishErr error
+		wantHook  string
+	}{
+		{
+			name:     "commit prepared",
+			finish:   storage.Preparer.CommitPreparedCtx,
+			command:  "COMMIT PREPARED 'storage_[0-9a-f]+'",
+			wantHook: "commit",
+		},
+		{
+			name:     "rollback prepared",
+			finish:   storage.Preparer.RollbackPreparedCtx,
+			command:  "ROLLBACK PREPARED 'storage_[0-9a-f]+'",
+			wantHook: "rollback",
+		},
+		{
+			name:      "commit prepared failed",
+			finish:    storage.Preparer.CommitPreparedCtx,
+			command:   "COMMIT PREPARED 'storage_[0-9a-f]+'",
+			finishErr: errors.New("connection lost"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			transactor := NewTransactor(conn)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("PREPARE TRANSACTION 'storage_[0-9a-f]+'").WillReturnResult(sqlmock.NewResult(0, 0))
+			// transaction object is closed after prepare, prepared transaction is finished by another connection
+			mock.ExpectRollback()
+			finish := mock.ExpectExec(tt.command)
+			if tt.finishErr != nil {
+				finish.WillReturnError(tt.finishErr)
+			} else {
+				finish.WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			ctx, err := transactor.BeginCtx(context.Background())
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+
+			var hook string
+			_ = storage.OnCommit(ctx, func(ctx context.Context) error {
+				hook = "commit"
+				return nil
+			})
+			_ = storage.OnRollback(ctx, func(ctx context.Context) error {
+				hook = "rollback"
+				return nil
+			})
+
+			preparer := transactor.(storage.Preparer)
+			if err = preparer.PrepareCtx(ctx); err != nil {
+				t.Fatalf("prepare: %v", err)
+			}
+
+			if err = tt.finish(preparer, ctx); !errors.Is(err, tt.finishErr) {
+				t.Fatalf("expected error %v, got %v", tt.finishErr, err)
+			}
+
+			if hook != tt.wantHook {
+				t.Fatalf("expected %q hooks to run, got %q", tt.wantHook, hook)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// txProvider is provider which connection pool is unknown
+type txProvider struct {
+	conn *sqlx.DB
+}
+
+func (p txProvider) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return p.conn.BeginTxx(ctx, opts)
+}
+
+func TestPrepareUnknownConnection(t *testing.T) {
+	conn, mock := newMock(t)
+	transactor := NewTransactor(txProvider{conn: conn})
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	ctx, err := transactor.BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if err = transactor.(storage.Preparer).PrepareCtx(ctx); !errors.Is(err, ErrPrepareNotSupported) {
+		t.Fatalf("expected prepare not supported error, got %v", err)
+	}
+
+	// not prepared transaction is rolled back as usual
+	if err = transactor.(storage.Preparer).RollbackPreparedCtx(ctx); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreparedID(t *testing.T) {
+	globalCtx, globalID, err := storage.BeginGlobalTx(context.Background())
+	if err != nil {
+		t.Fatalf("begin global transaction: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		wantGID      string
+		wantGlobalID string
+	}{
+		{
+			name:         "first branch",
+			ctx:          globalCtx,
+			wantGID:      "storage_" + globalID + "_1",
+			wantGlobalID: globalID,
+		},
+		{
+			name:         "second branch",
+			ctx:          globalCtx,
+			wantGID:      "storage_" + globalID + "_2",
+			wantGlobalID: globalID,
+		},
+		{
+			name: "without global transaction",
+			ctx:  context.Background(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gid, err := newPreparedID(tt.ctx)
+			if err != nil {
+				t.Fatalf("new prepared id: %v", err)
+			}
+
+			if tt.wantGID != "" && gid != tt.wantGID {
+				t.Fatalf("expected gid %q, got %q", tt.wantGID, gid)
+			}
+
+			// random identifier is global identifier itself, so it is never found in decisions
+			wantGlobalID := tt.wantGlobalID
+			if wantGlobalID == "" {
+				wantGlobalID = strings.TrimPrefix(gid, preparedPrefix)
+			}
+
+			if id := preparedGlobalID(gid); id != wantGlobalID {
+				t.Fatalf("expected global id %q, got %q", wantGlobalID, id)
+			}
+		})
+	}
+}
+
+func TestRecoverPrepared(t *testing.T) {
+	prepared := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	errConn := errors.New("connection lost")
+
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		decisions storage.DecisionStore
+		expect    func(mock sqlmock.Sqlmock)
+		want      []string
+		wantErr   error
+	}{
+		{
+			name:      "zero age",
+			olderThan: 0,
+			decisions: &decisionsStub{},
+			wantErr:   ErrRecoverAgeInvalid,
+		},
+		{
+			name:      "decision store missing",
+			olderThan: time.Minute,
+			wantErr:   storage.ErrDecisionStoreMissing,
+		},
+		{
+			name:      "rollback without decision",
+			olderThan: time.Minute,
+			decisions: &decisionsStub{saved: map[string]bool{}},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("ROLLBACK PREPARED 'storage_a_1'").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ROLLBACK PREPARED 'storage_b_1'").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ROLLBACK PREPARED 'storage_b_2'").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want: []string{"storage_a_1", "storage_b_1", "storage_b_2"},
+		},
+		{
+			name:      "commit by decision",
+			olderThan: time.Minute,
+			decisions: &decisionsStub{saved: map[string]bool{"b": true}},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("ROLLBACK PREPARED 'storage_a_1'").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("COMMIT PREPARED 'storage_b_1'").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("COMMIT PREPARED 'storage_b_2'").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want: []string{"storage_a_1", "storage_b_1", "storage_b_2"},
+		},
+		{
+			name:      "decision not loaded",
+			olderThan: time.Minute,
+			decisions: &decisionsStub{checkErr: errConn},
+			expect:    func(mock sqlmock.Sqlmock) {},
+			want:      []string{},
+			wantErr:   errConn,
+		},
+		{
+			name:      "stop on error",
+			olderThan: time.Minute,
+			decisions: &decisionsStub{saved: map[string]bool{}},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("ROLLBACK PREPARED 'storage_a_1'").WillReturnError(errConn)
+			},
+			want:    []string{},
+			wantErr: ErrTransactorRollback,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			if tt.expect != nil {
+				mock.ExpectQuery("FROM pg_prepared_xacts").
+					WithArgs(preparedPrefix+"%", tt.olderThan.Seconds()).
+					WillReturnRows(sqlmock.NewRows([]string{"gid", "prepared", "owner", "database"}).
+						AddRow("storage_a_1", prepared, "app", "db").
+						AddRow("storage_b_1", prepared, "app", "db").
+						AddRow("storage_b_2", prepared, "app", "db"))
+				tt.expect(mock)
+			}
+
+			resolved, err := RecoverPrepared(context.Background(), conn, tt.decisions, tt.olderThan)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if !slices.Equal(resolved, tt.want) {
+				t.Fatalf("expected resolved %v, got %v", tt.want, resolved)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
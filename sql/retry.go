@@ -0,0 +1,291 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/storage"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes of errors which can be fixed by retrying transaction
+const (
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+)
+
+// SQLSTATE codes of transient errors which can be fixed by retrying query on another connection
+const (
+	CodeTooManyConnections = "53300"
+	CodeAdminShutdown      = "57P01"
+)
+
+const idempotentKey = "storage_sql_idempotent"
+
+// RetryPolicy describes how many times and how often failed operation is retried
+type RetryPolicy struct {
+	// MaxAttempts is max count of runs including the first one
+	MaxAttempts int
+	// MinBackoff is delay before the second attempt. Every next delay is doubled
+	MinBackoff time.Duration
+	// MaxBackoff limits delay between attempts
+	MaxBackoff time.Duration
+	// Codes is list of SQLSTATE codes which should be retried
+	Codes []string
+	// ConnectionErrors enables retry of broken connection errors (driver.ErrBadConn, connection reset)
+	ConnectionErrors bool
+}
+
+// DefaultRetryPolicy returns policy which retries serialization failures & deadlocks 3 times
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond * 20,
+		MaxBackoff:  time.Second,
+		Codes: []string{
+			CodeSerializationFailure,
+			CodeDeadlockDetected,
+		},
+	}
+}
+
+// TransientRetryPolicy returns policy which retries broken connections, too many connections
+// and admin shutdown errors 3 times
+func TransientRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond * 50,
+		MaxBackoff:  time.Second,
+		Codes: []string{
+			CodeTooManyConnections,
+			CodeAdminShutdown,
+		},
+		ConnectionErrors: true,
+	}
+}
+
+// Retryable checks if provided error contain one of policy SQLSTATE codes
+// or it is broken connection error if policy retries them
+func (policy RetryPolicy) Retryable(err error) bool {
+	if policy.ConnectionErrors && isBrokenConnection(err) {
+		return true
+	}
+
+	code := ErrorCode(err)
+	if code == "" {
+		return false
+	}
+
+	return slices.Contains(policy.Codes, code)
+}
+
+// Backoff returns delay before next attempt.
+//
+// Delay grows exponentially and contain random jitter to spread competing retries
+func (policy RetryPolicy) Backoff(attempt int) time.Duration {
+	if policy.MinBackoff <= 0 {
+		return 0
+	}
+
+	// without MaxBackoff delay is capped by max duration, so doubling does not overflow
+	limit := policy.MaxBackoff
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	backoff := policy.MinBackoff
+	for i := 1; i < attempt; i++ {
+		if backoff >= limit/2 {
+			backoff = limit
+			break
+		}
+
+		backoff *= 2
+	}
+
+	half := int64(backoff / 2)
+	// nolint:gosec // jitter does not need crypto random
+	return time.Duration(half + rand.Int64N(half+1))
+}
+
+// wait sleeps before provided attempt or returns context error if context done earlier.
+//
+// If context deadline comes earlier than the end of delay, error is returned without waiting
+func (policy RetryPolicy) wait(ctx context.Context, attempt int) error {
+	backoff := policy.Backoff(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isBrokenConnection checks if error means connection was broken before query was done
+func isBrokenConnection(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		pgconn.SafeToRetry(err)
+}
+
+// ErrorCode returns SQLSTATE code of provided error.
+//
+// Supports errors of lib/pq & pgx drivers. Returns empty string if error is not database error
+func ErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
+
+// AtomicRetry do the same as Atomic but re-runs fn in a fresh transaction if it failed with retryable error.
+//
+// By default, DefaultRetryPolicy is used.
+// If context already contain transaction, fn runs only once because retry of the part of outer transaction
+// can not fix serialization failure - the whole outer transaction must be retried
+func AtomicRetry(
+	ctx context.Context,
+	conn *sqlx.DB,
+	fn func(ctx context.Context) error,
+	policy ...RetryPolicy,
+) error {
+	if _, ok := GetTx(ctx); ok {
+		return Atomic(ctx, conn, fn)
+	}
+
+	retryPolicy := DefaultRetryPolicy()
+	if len(policy) > 0 {
+		retryPolicy = policy[0]
+	}
+
+	return retryPolicy.run(ctx, func() error {
+		return Atomic(ctx, conn, fn)
+	})
+}
+
+// AtomicTransactor runs fn inside transaction of provided transactor and re-runs it in a fresh transaction
+// if fn or commit failed with retryable error.
+//
+// Transactor.TryCommit can not retry because it does not own fn, so use AtomicTransactor instead of BeginCtx & TryCommit pair.
+// Works with composite transactor (storage.NewTransactor) too. By default, DefaultRetryPolicy is used.
+// If context already contain transaction of transactor, fn runs only once (see AtomicRetry)
+func AtomicTransactor(
+	ctx context.Context,
+	transactor storage.Transactor,
+	fn func(ctx context.Context) error,
+	policy ...RetryPolicy,
+) error {
+	run := func() error {
+		txCtx, err := transactor.BeginCtx(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = errorx.Try(func() error {
+			return fn(txCtx)
+		}); err != nil {
+			_ = transactor.RollbackCtx(txCtx)
+			return err
+		}
+
+		return transactor.CommitCtx(txCtx)
+	}
+
+	if transactor.IsTx(ctx) {
+		return run()
+	}
+
+	retryPolicy := DefaultRetryPolicy()
+	if len(policy) > 0 {
+		retryPolicy = policy[0]
+	}
+
+	return retryPolicy.run(ctx, run)
+}
+
+// run calls fn until it succeeds, returns not retryable error or attempts are over
+func (policy RetryPolicy) run(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+
+		if waitErr := policy.wait(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// Idempotent marks context, so exec queries with this context can be retried by RetryOption (RetryInterceptor)
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey, true)
+}
+
+// IsIdempotent checks if context is marked by Idempotent
+func IsIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey).(bool)
+	return idempotent
+}
+
+// RetryOption enables retry of failed queries by provided policy. See RetryInterceptor
+func RetryOption(policy RetryPolicy) ClientOption {
+	return func(options *clientOptions) {
+		options.retry = &policy
+	}
+}
+
+// RetryInterceptor re-runs query call if it failed with error retryable by policy.
+//
+// Only reads & prepares are retried. ExecContext and NamedExecContext are retried only if context marked by Idempotent.
+// Query is never retried inside transaction because broken transaction can not be continued.
+// Retry stops if context deadline comes earlier than the next attempt
+func RetryInterceptor(policy RetryPolicy) Interceptor {
+	return func(ctx context.Context, call *QueryCall, next Handler) error {
+		if !retryAllowed(ctx, call) {
+			return next(ctx, call)
+		}
+
+		return policy.run(ctx, func() error {
+			return next(ctx, call)
+		})
+	}
+}
+
+// retryAllowed checks if query call can be safely run again
+func retryAllowed(ctx context.Context, call *QueryCall) bool {
+	if _, ok := GetTx(ctx); ok {
+		return false
+	}
+
+	switch call.Method {
+	case MethodExec, MethodNamedExec:
+		return IsIdempotent(ctx)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,322 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		err    error
+		want   bool
+	}{
+		{name: "pq serialization failure", policy: DefaultRetryPolicy(), err: &pq.Error{Code: CodeSerializationFailure}, want: true},
+		{name: "pgx deadlock", policy: DefaultRetryPolicy(), err: &pgconn.PgError{Code: CodeDeadlockDetected}, want: true},
+		{name: "unique violation", policy: DefaultRetryPolicy(), err: &pq.Error{Code: "23505"}, want: false},
+		{name: "plain error", policy: DefaultRetryPolicy(), err: errors.New("boom"), want: false},
+		{name: "wrapped code", policy: DefaultRetryPolicy(), err: ErrTransactorCommit.SetError(&pq.Error{Code: CodeSerializationFailure}), want: true},
+		{name: "bad conn without connection errors", policy: DefaultRetryPolicy(), err: driver.ErrBadConn, want: false},
+		{name: "bad conn with connection errors", policy: TransientRetryPolicy(), err: driver.ErrBadConn, want: true},
+		{name: "admin shutdown", policy: TransientRetryPolicy(), err: &pq.Error{Code: CodeAdminShutdown}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Retryable(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MinBackoff: time.Millisecond * 10,
+		MaxBackoff: time.Millisecond * 50,
+	}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Millisecond * 10},
+		{attempt: 2, max: time.Millisecond * 20},
+		{attempt: 3, max: time.Millisecond * 40},
+		{attempt: 4, max: time.Millisecond * 50},
+		{attempt: 10, max: time.Millisecond * 50},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			backoff := policy.Backoff(tt.attempt)
+			if backoff < tt.max/2 || backoff > tt.max {
+				t.Fatalf("attempt %d: backoff %v is out of [%v, %v]", tt.attempt, backoff, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	// doubling without MaxBackoff does not overflow
+	unlimited := RetryPolicy{MinBackoff: time.Millisecond * 10}
+	for _, attempt := range []int{64, 1000, math.MaxInt32} {
+		if backoff := unlimited.Backoff(attempt); backoff < math.MaxInt64/2 {
+			t.Fatalf("attempt %d: expected backoff capped by max duration, got %v", attempt, backoff)
+		}
+	}
+
+	if backoff := (RetryPolicy{}).Backoff(3); backoff != 0 {
+		t.Fatalf("expected zero backoff without MinBackoff, got %v", backoff)
+	}
+}
+
+func TestRetryPolicyWaitDeadline(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: time.Second, MaxBackoff: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	start := time.Now()
+	if err := policy.wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Millisecond*100 {
+		t.Fatalf("wait did not return early: %v", elapsed)
+	}
+}
+
+func noWaitPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MinBackoff = 0
+	return policy
+}
+
+func TestAtomicRetry(t *testing.T) {
+	serialization := &pq.Error{Code: CodeSerializationFailure}
+
+	tests := []struct {
+		name     string
+		failures int
+		wantRuns int
+		wantErr  bool
+	}{
+		{name: "success at first attempt", failures: 0, wantRuns: 1},
+		{name: "success after retry", failures: 2, wantRuns: 3},
+		{name: "attempts are over", failures: 3, wantRuns: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			for run := 1; run <= tt.wantRuns; run++ {
+				mock.ExpectBegin()
+				if run <= tt.failures {
+					mock.ExpectExec("UPDATE").WillReturnError(serialization)
+					mock.ExpectRollback()
+					continue
+				}
+
+				mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			runs := 0
+			err := AtomicRetry(context.Background(), conn, func(ctx context.Context) error {
+				runs++
+				_, err := NewClient(conn).ExecContext(ctx, "UPDATE accounts SET balance = 0")
+				return err
+			}, noWaitPolicy())
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if runs != tt.wantRuns {
+				t.Fatalf("expected %d runs, got %d", tt.wantRuns, runs)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAtomicRetryInsideTransaction(t *testing.T) {
+	conn, mock := newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	runs := 0
+	_ = Atomic(context.Background(), conn, func(ctx context.Context) error {
+		return AtomicRetry(ctx, conn, func(ctx context.Context) error {
+			runs++
+			return &pq.Error{Code: CodeSerializationFailure}
+		}, noWaitPolicy())
+	})
+
+	if runs != 1 {
+		t.Fatalf("expected fn to run once inside outer transaction, got %d", runs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAtomicTransactor(t *testing.T) {
+	conn, mock := newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(&pq.Error{Code: CodeSerializationFailure})
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	runs := 0
+	err := AtomicTransactor(context.Background(), NewTransactor(conn), func(ctx context.Context) error {
+		runs++
+		return nil
+	}, noWaitPolicy())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", runs)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		inTx       bool
+		want       bool
+	}{
+		{name: "select", method: MethodSelect, want: true},
+		{name: "get", method: MethodGet, want: true},
+		{name: "query row", method: MethodQueryRowx, want: true},
+		{name: "prepare", method: MethodPrepare, want: true},
+		{name: "exec", method: MethodExec, want: false},
+		{name: "named exec", method: MethodNamedExec, want: false},
+		{name: "idempotent exec", method: MethodExec, idempotent: true, want: true},
+		{name: "idempotent named exec", method: MethodNamedExec, idempotent: true, want: true},
+		{name: "select inside transaction", method: MethodSelect, inTx: true, want: false},
+		{name: "idempotent exec inside transaction", method: MethodExec, idempotent: true, inTx: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = Idempotent(ctx)
+			}
+
+			if tt.inTx {
+				ctx = SetTx(ctx, &sqlx.Tx{})
+			}
+
+			if got := retryAllowed(ctx, &QueryCall{Method: tt.method}); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	if IsIdempotent(context.Background()) {
+		t.Fatal("expected not marked context")
+	}
+
+	if !IsIdempotent(Idempotent(context.Background())) {
+		t.Fatal("expected marked context")
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	shutdown := &pq.Error{Code: CodeAdminShutdown}
+
+	tests := []struct {
+		name       string
+		query      string
+		exec       bool
+		idempotent bool
+		failures   int
+		wantCalls  int
+		wantErr    bool
+	}{
+		{name: "select recovered", query: "SELECT id FROM users", failures: 2, wantCalls: 3},
+		{name: "select attempts are over", query: "SELECT id FROM users", failures: 3, wantCalls: 3, wantErr: true},
+		{name: "exec is not retried", query: "UPDATE users", exec: true, failures: 1, wantCalls: 1, wantErr: true},
+		{name: "idempotent exec recovered", query: "UPDATE users", exec: true, idempotent: true, failures: 1, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			for idx := range tt.wantCalls {
+				if tt.exec {
+					expect := mock.ExpectExec(tt.query)
+					if idx < tt.failures {
+						expect.WillReturnError(shutdown)
+					} else {
+						expect.WillReturnResult(sqlmock.NewResult(0, 1))
+					}
+					continue
+				}
+
+				expect := mock.ExpectQuery(tt.query)
+				if idx < tt.failures {
+					expect.WillReturnError(shutdown)
+				} else {
+					expect.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				}
+			}
+
+			policy := TransientRetryPolicy()
+			policy.MinBackoff = 0
+			client := NewClient(conn, RetryOption(policy))
+
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = Idempotent(ctx)
+			}
+
+			var err error
+			if tt.exec {
+				_, err = client.ExecContext(ctx, tt.query+" SET name = 'a'")
+			} else {
+				var ids []int
+				err = client.SelectContext(ctx, &ids, tt.query)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			// query sent more times than expected fails by sqlmock error
+			if tt.wantErr && ErrorCode(err) != CodeAdminShutdown {
+				t.Fatalf("expected admin shutdown error, got %v", err)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
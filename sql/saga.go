@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/boostgo/storage"
+)
+
+type sagaStore struct {
+	db DB
+}
+
+// NewSagaStore creates storage.SagaStore based on "storage_sagas" table.
+//
+// Table is created by MigrateStorage. If shard client is provided, state is saved to the shard
+// chosen by selector and pending sagas are collected from all shards
+func NewSagaStore(db DB) storage.SagaStore {
+	return &sagaStore{
+		db: db,
+	}
+}
+
+func (store *sagaStore) Save(ctx context.Context, state storage.SagaState) error {
+	const query = `
+INSERT INTO storage_sagas (id, name, status, step, payload, error, created_at, updated_at)
+VALUES (:id, :name, :status, :step, :payload, :error, :created_at, :updated_at)
+ON CONFLICT (id) DO UPDATE
+SET status     = excluded.status,
+    step       = excluded.step,
+    error      = excluded.error,
+    updated_at = excluded.updated_at`
+
+	_, err := store.db.NamedExecContext(ctx, query, state)
+	return err
+}
+
+func (store *sagaStore) Pending(ctx context.Context, name string, updatedBefore time.Time) ([]storage.SagaState, error) {
+	const query = `
+SELECT id, name, status, step, payload, error, created_at, updated_at
+FROM storage_sagas
+WHERE name = $1 AND status IN ($2, $3, $4) AND updated_at < $5
+ORDER BY created_at`
+
+	states := make([]storage.SagaState, 0)
+	err := eachConn(store.db, func(conn DB) error {
+		shardStates := make([]storage.SagaState, 0)
+		if err := conn.SelectContext(
+			ctx,
+			&shardStates,
+			query,
+			name, storage.SagaRunning, storage.SagaCompensating, storage.SagaFailed, updatedBefore,
+		); err != nil {
+			return err
+		}
+
+		states = append(states, shardStates...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (store *sagaStore) Claim(ctx context.Context, state storage.SagaState, claimedAt time.Time) (bool, error) {
+	const query = `
+UPDATE storage_sagas
+SET updated_at = $1
+WHERE id = $2 AND updated_at = $3`
+
+	var claimed int64
+	err := eachConn(store.db, func(conn DB) error {
+		result, err := conn.ExecContext(ctx, query, claimedAt, state.ID, state.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		claimed += rowsAffected(result)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return claimed > 0, nil
+}
@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+)
+
+func TestSagaStorePending(t *testing.T) {
+	conn, mock := newMock(t)
+	store := NewSagaStore(NewClient(conn))
+
+	updatedBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM storage_sagas").
+		WithArgs("test", storage.SagaRunning, storage.SagaCompensating, storage.SagaFailed, updatedBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "step", "payload", "error", "created_at", "updated_at"}).
+			AddRow("1", "test", storage.SagaRunning, 1, nil, "", updatedBefore, updatedBefore))
+
+	states, err := store.Pending(context.Background(), "test", updatedBefore)
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+
+	if len(states) != 1 || states[0].ID != "1" {
+		t.Fatalf("unexpected states: %+v", states)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSagaStoreClaim(t *testing.T) {
+	updatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	claimedAt := updatedAt.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		affected int64
+		execErr  error
+		want     bool
+		wantErr  bool
+	}{
+		{name: "claimed", affected: 1, want: true},
+		{name: "claimed by another process", affected: 0, want: false},
+		{name: "failed", execErr: errors.New("connection lost"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			store := NewSagaStore(NewClient(conn))
+
+			exec := mock.ExpectExec("UPDATE storage_sagas").WithArgs(claimedAt, "1", updatedAt)
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			}
+
+			claimed, err := store.Claim(context.Background(), storage.SagaState{ID: "1", UpdatedAt: updatedAt}, claimedAt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if claimed != tt.want {
+				t.Fatalf("expected claimed %v, got %v", tt.want, claimed)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package sql
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ShardErrors contain errors of shards by shard keys
+type ShardErrors map[string]error
+
+func (errs ShardErrors) Error() string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, key+": "+errs[key].Error())
+	}
+
+	return "shard errors: " + strings.Join(messages, "; ")
+}
+
+func (errs ShardErrors) Unwrap() []error {
+	unwrapped := make([]error, 0, len(errs))
+	for _, err := range errs {
+		unwrapped = append(unwrapped, err)
+	}
+	return unwrapped
+}
+
+// ScatterQuery runs select query on every shard and merges results
+type ScatterQuery[T any] struct {
+	db           DB
+	less         func(a, b T) bool
+	mergeSorted  bool
+	limit        int
+	offset       int
+	shardTimeout time.Duration
+	partial      bool
+	concurrency  int
+}
+
+// Scatter creates scatter query by shard client. For single client query runs once
+func Scatter[T any](db DB) *ScatterQuery[T] {
+	return &ScatterQuery[T]{
+		db: db,
+	}
+}
+
+// SelectAll runs query on every shard in parallel and merges rows into one slice
+func SelectAll[T any](ctx context.Context, db DB, query string, args ...any) ([]T, error) {
+	return Scatter[T](db).Select(ctx, query, args...)
+}
+
+// Sort sets order of merged rows
+func (q *ScatterQuery[T]) Sort(less func(a, b T) bool) *ScatterQuery[T] {
+	q.less = less
+	q.mergeSorted = false
+	return q
+}
+
+// MergeSorted sets order of merged rows if query returns rows of every shard already sorted in the same order.
+//
+// Rows are merged by k-way merge, so only rows within limit are compared
+func (q *ScatterQuery[T]) MergeSorted(less func(a, b T) bool) *ScatterQuery[T] {
+	q.less = less
+	q.mergeSorted = true
+	return q
+}
+
+// Limit sets global limit & offset of merged rows.
+//
+// Query of every shard should contain its own "LIMIT offset+limit" to not load unnecessary rows
+func (q *ScatterQuery[T]) Limit(limit, offset int) *ScatterQuery[T] {
+	q.limit = limit
+	q.offset = offset
+	return q
+}
+
+// ShardTimeout sets timeout of query on every shard
+func (q *ScatterQuery[T]) ShardTimeout(timeout time.Duration) *ScatterQuery[T] {
+	q.shardTimeout = timeout
+	return q
+}
+
+// Partial enables partial results mode.
+//
+// Failed shards do not stop other shards and Select returns rows of succeeded shards with ShardErrors
+func (q *ScatterQuery[T]) Partial() *ScatterQuery[T] {
+	q.partial = true
+	return q
+}
+
+// Concurrency limits count of shards queried at the same time
+func (q *ScatterQuery[T]) Concurrency(concurrency int) *ScatterQuery[T] {
+	q.concurrency = concurrency
+	return q
+}
+
+// Select runs query on every shard and merges rows.
+//
+// If one of shards failed, ShardErrors is returned. Without Partial mode other shards are cancelled, rows are not returned
+// and ShardErrors contain only errors of shards which failed before cancellation.
+// Scatter query can not run inside transaction because transaction belongs to single shard
+func (q *ScatterQuery[T]) Select(ctx context.Context, query string, args ...any) ([]T, error) {
+	if _, ok := GetTx(ctx); ok {
+		return nil, ErrScatterTransaction
+	}
+
+	iterator, ok := q.db.(shardIterator)
+	if !ok || !iterator.sharded() {
+		var rows []T
+		if err := q.db.SelectContext(ctx, &rows, query, args...); err != nil {
+			return nil, err
+		}
+
+		return q.merge([][]T{rows}), nil
+	}
+
+	connections := iterator.shards()
+	results := make([][]T, len(connections))
+
+	var (
+		mx   sync.Mutex
+		errs = ShardErrors{}
+	)
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	if q.partial {
+		wg = &errgroup.Group{}
+		wgCtx = ctx
+	}
+	if q.concurrency > 0 {
+		wg.SetLimit(q.concurrency)
+	}
+
+	for idx, conn := range connections {
+		wg.Go(func() error {
+			shardCtx := wgCtx
+			if q.shardTimeout > 0 {
+				var cancel context.CancelFunc
+				shardCtx, cancel = context.WithTimeout(shardCtx, q.shardTimeout)
+				defer cancel()
+			}
+
+			var rows []T
+			if err := iterator.shardConn(conn).SelectContext(shardCtx, &rows, query, args...); err != nil {
+				mx.Lock()
+				defer mx.Unlock()
+
+				// shards cancelled because of failed shard are not failed themselves
+				if !q.partial && len(errs) > 0 && wgCtx.Err() != nil {
+					return err
+				}
+
+				errs[conn.Key()] = err
+				return err
+			}
+
+			results[idx] = rows
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	if len(errs) > 0 && !q.partial {
+		return nil, errs
+	}
+
+	merged := q.merge(results)
+	if len(errs) > 0 {
+		return merged, errs
+	}
+
+	return merged, nil
+}
+
+// merge merges rows of shards by order and applies limit & offset
+func (q *ScatterQuery[T]) merge(results [][]T) []T {
+	if q.less != nil && q.mergeSorted {
+		return q.kWayMerge(results)
+	}
+
+	total := 0
+	for _, rows := range results {
+		total += len(rows)
+	}
+
+	merged := make([]T, 0, total)
+	for _, rows := range results {
+		merged = append(merged, rows...)
+	}
+
+	if q.less != nil {
+		sort.SliceStable(merged, func(i, j int) bool {
+			return q.less(merged[i], merged[j])
+		})
+	}
+
+	return q.page(merged)
+}
+
+// kWayMerge merges sorted rows of shards until limit is reached
+func (q *ScatterQuery[T]) kWayMerge(results [][]T) []T {
+	h := &mergeHeap[T]{
+		less: q.less,
+	}
+	for idx, rows := range results {
+		if len(rows) > 0 {
+			h.items = append(h.items, mergeItem{shard: idx})
+		}
+	}
+	h.results = results
+	heap.Init(h)
+
+	want := -1
+	if q.limit > 0 {
+		want = q.offset + q.limit
+	}
+
+	merged := make([]T, 0)
+	for h.Len() > 0 && (want < 0 || len(merged) < want) {
+		item := h.items[0]
+		merged = append(merged, results[item.shard][item.row])
+
+		if item.row+1 < len(results[item.shard]) {
+			h.items[0].row++
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+
+	return q.page(merged)
+}
+
+// page applies offset & limit to merged rows
+func (q *ScatterQuery[T]) page(rows []T) []T {
+	if q.offset > 0 {
+		if q.offset >= len(rows) {
+			return []T{}
+		}
+
+		rows = rows[q.offset:]
+	}
+
+	if q.limit > 0 && q.limit < len(rows) {
+		rows = rows[:q.limit]
+	}
+
+	return rows
+}
+
+type mergeItem struct {
+	shard int
+	row   int
+}
+
+// mergeHeap is heap of current rows of every shard
+type mergeHeap[T any] struct {
+	items   []mergeItem
+	results [][]T
+	less    func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int {
+	return len(h.items)
+}
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	return h.less(h.results[a.shard][a.row], h.results[b.shard][b.row])
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *mergeHeap[T]) Push(x any) {
+	h.items = append(h.items, x.(mergeItem))
+}
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
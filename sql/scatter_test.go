@@ -0,0 +1,216 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestScatterMerge(t *testing.T) {
+	less := func(a, b int) bool {
+		return a < b
+	}
+
+	results := [][]int{
+		{1, 4, 7},
+		{2, 5, 8, 9},
+		{},
+		{3, 6},
+	}
+
+	tests := []struct {
+		name  string
+		query func(q *ScatterQuery[int]) *ScatterQuery[int]
+		want  []int
+	}{
+		{
+			name:  "unordered",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q },
+			want:  []int{1, 4, 7, 2, 5, 8, 9, 3, 6},
+		},
+		{
+			name:  "sort",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.Sort(less) },
+			want:  []int{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name:  "merge sorted",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.MergeSorted(less) },
+			want:  []int{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name:  "sort with page",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.Sort(less).Limit(3, 2) },
+			want:  []int{3, 4, 5},
+		},
+		{
+			name:  "merge sorted with page",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.MergeSorted(less).Limit(3, 2) },
+			want:  []int{3, 4, 5},
+		},
+		{
+			name:  "merge sorted with limit only",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.MergeSorted(less).Limit(4, 0) },
+			want:  []int{1, 2, 3, 4},
+		},
+		{
+			name:  "limit over rows",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.MergeSorted(less).Limit(5, 7) },
+			want:  []int{8, 9},
+		},
+		{
+			name:  "offset over rows",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.Sort(less).Limit(5, 9) },
+			want:  []int{},
+		},
+		{
+			name:  "merge sorted offset over rows",
+			query: func(q *ScatterQuery[int]) *ScatterQuery[int] { return q.MergeSorted(less).Limit(5, 20) },
+			want:  []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.query(Scatter[int](nil)).merge(results)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeSortedEqualRows(t *testing.T) {
+	type row struct {
+		shard string
+		value int
+	}
+
+	// rows with equal values of different shards are all merged
+	results := [][]row{
+		{{shard: "a", value: 1}, {shard: "a", value: 3}},
+		{{shard: "b", value: 1}, {shard: "b", value: 2}},
+	}
+
+	got := Scatter[row](nil).
+		MergeSorted(func(a, b row) bool {
+			return a.value < b.value
+		}).
+		merge(results)
+
+	values := make([]int, 0, len(got))
+	for _, r := range got {
+		values = append(values, r.value)
+	}
+
+	if !slices.Equal(values, []int{1, 1, 2, 3}) {
+		t.Fatalf("expected merged values, got %v", got)
+	}
+}
+
+// newScatterShards creates shard client of sqlmock connections with keys "a" & "b"
+func newScatterShards(t *testing.T) (DB, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	connA, mockA := newMock(t)
+	connB, mockB := newMock(t)
+
+	connections := newConnections([]ShardConnect{
+		newShardConnect("a", nil, connA),
+		newShardConnect("b", nil, connB),
+	}, LookupSelector(nil))
+
+	return NewClientShard(connections), mockA, mockB
+}
+
+func TestScatterSelect(t *testing.T) {
+	errShard := errors.New("shard is down")
+
+	tests := []struct {
+		name     string
+		partial  bool
+		failB    bool
+		want     []int
+		wantErrs []string
+	}{
+		{name: "all shards", want: []int{1, 2, 3, 4}},
+		{name: "failed shard", failB: true, wantErrs: []string{"b"}},
+		{name: "partial failed shard", partial: true, failB: true, want: []int{1, 3}, wantErrs: []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mockA, mockB := newScatterShards(t)
+
+			rowsA := sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3)
+			if tt.failB && !tt.partial {
+				// shard "a" is cancelled by failure of shard "b"
+				mockA.ExpectQuery("SELECT id FROM users").WillDelayFor(time.Second).WillReturnRows(rowsA)
+			} else {
+				mockA.ExpectQuery("SELECT id FROM users").WillReturnRows(rowsA)
+			}
+
+			if tt.failB {
+				mockB.ExpectQuery("SELECT id FROM users").WillReturnError(errShard)
+			} else {
+				mockB.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(4))
+			}
+
+			query := Scatter[int](db).Sort(func(a, b int) bool {
+				return a < b
+			})
+			if tt.partial {
+				query.Partial()
+			}
+
+			rows, err := query.Select(context.Background(), "SELECT id FROM users ORDER BY id")
+			if !slices.Equal(rows, tt.want) {
+				t.Fatalf("expected rows %v, got %v", tt.want, rows)
+			}
+
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("select: %v", err)
+				}
+				return
+			}
+
+			var errs ShardErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected shard errors, got %v", err)
+			}
+
+			keys := make([]string, 0, len(errs))
+			for key := range errs {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+
+			if !slices.Equal(keys, tt.wantErrs) {
+				t.Fatalf("expected errors of shards %v, got %v", tt.wantErrs, errs)
+			}
+
+			if !errors.Is(errs["b"], errShard) {
+				t.Fatalf("expected error of shard b, got %v", errs["b"])
+			}
+		})
+	}
+}
+
+func TestScatterInsideTransaction(t *testing.T) {
+	db, _, _ := newScatterShards(t)
+
+	conn, mock := newMock(t)
+	mock.ExpectBegin()
+	tx, err := conn.Beginx()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	_, err = SelectAll[int](SetTx(context.Background(), tx), db, "SELECT id FROM users")
+	if !errors.Is(err, ErrScatterTransaction) {
+		t.Fatalf("expected scatter transaction error, got %v", err)
+	}
+}
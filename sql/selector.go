@@ -0,0 +1,40 @@
+package sql
+
+import "github.com/boostgo/storage"
+
+// HashSelector chooses shard by consistent hashing of shard key set by storage.WithShardKey.
+// See storage.HashSelector
+func HashSelector(virtualNodes int) ConnectionSelector {
+	return ConnectionSelector(storage.HashSelector[ShardConnect](virtualNodes))
+}
+
+// RendezvousSelector chooses shard by rendezvous hashing of shard key set by storage.WithShardKey
+func RendezvousSelector() ConnectionSelector {
+	return ConnectionSelector(storage.RendezvousSelector[ShardConnect]())
+}
+
+// ModuloSelector chooses shard by numeric shard key set by storage.WithShardKey modulo count of shards
+func ModuloSelector() ConnectionSelector {
+	return ConnectionSelector(storage.ModuloSelector[ShardConnect]())
+}
+
+// NumericRangeSelector chooses shard which range contain numeric shard key set by storage.WithShardKey
+func NumericRangeSelector(ranges ...storage.NumericRange) ConnectionSelector {
+	return ConnectionSelector(storage.NumericRangeSelector[ShardConnect](ranges...))
+}
+
+// LexicalRangeSelector chooses shard which range contain shard key set by storage.WithShardKey
+func LexicalRangeSelector(ranges ...storage.LexicalRange) ConnectionSelector {
+	return ConnectionSelector(storage.LexicalRangeSelector[ShardConnect](ranges...))
+}
+
+// LookupSelector chooses shard by static map of shard keys set by storage.WithShardKey to connection keys
+func LookupSelector(lookup map[string]string) ConnectionSelector {
+	return ConnectionSelector(storage.LookupSelector[ShardConnect](lookup))
+}
+
+// ConditionSelector chooses the first shard which conditions match values set by storage.WithShardValue.
+// See storage.ConditionSelector
+func ConditionSelector(fallback string) ConnectionSelector {
+	return ConnectionSelector(storage.ConditionSelector[ShardConnect](fallback))
+}
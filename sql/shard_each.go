@@ -0,0 +1,155 @@
+package sql
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/sync/errgroup"
+)
+
+// shardIterator is implemented by DB implementations which consist of shards
+type shardIterator interface {
+	// sharded returns false if DB acts as single client
+	sharded() bool
+	shards() []ShardConnect
+	shardConn(shard ShardConnect) DB
+	// shardBegin returns provider which begins transactions at provided shard
+	shardBegin(shard ShardConnect) TransactorConnectionProvider
+}
+
+// clientShardConn is client of single shard created from shard client.
+//
+// Acts as single client: returns shard connection by Connection and does not support EachShard
+type clientShardConn struct {
+	*clientShard
+	shard ShardConnect
+}
+
+func (c *clientShardConn) Connection() *sqlx.DB {
+	return c.shard.Conn()
+}
+
+func (c *clientShardConn) sharded() bool {
+	return false
+}
+
+func (c *clientShardConn) EachShard(_ func(conn DB) error) error {
+	return ErrMethodNotSuppoertedInSingle
+}
+
+func (c *clientShardConn) EachShardAsync(_ func(conn DB) error, _ ...int) error {
+	return ErrMethodNotSuppoertedInSingle
+}
+
+// pinnedSelector always chooses the only connection of client created by shardConn
+func pinnedSelector(_ context.Context, connections []ShardConnect) ShardConnect {
+	return connections[0]
+}
+
+// EachOption sets EachShardConnect & EachShardConnectAsync settings
+type EachOption func(options *eachOptions)
+
+type eachOptions struct {
+	continueOnError bool
+	limit           int
+}
+
+// ContinueOnErrorOption runs fn on every shard even if some of them failed
+func ContinueOnErrorOption() EachOption {
+	return func(options *eachOptions) {
+		options.continueOnError = true
+	}
+}
+
+// EachLimitOption limits count of goroutines of EachShardConnectAsync
+func EachLimitOption(limit int) EachOption {
+	return func(options *eachOptions) {
+		options.limit = limit
+	}
+}
+
+// EachShardConnect runs fn with every shard one by one.
+//
+// Fn receives shard connection (key & raw connection) and client of the shard which uses logger & interceptors of db.
+// By default, it stops at the first error. Errors are returned as ShardErrors keyed by shard key
+func EachShardConnect(db DB, fn func(shard ShardConnect, conn DB) error, options ...EachOption) error {
+	iterator, ok := db.(shardIterator)
+	if !ok || !iterator.sharded() {
+		return ErrConnectionIsNotShard
+	}
+
+	opts := newEachOptions(options...)
+	errs := ShardErrors{}
+	for _, shard := range iterator.shards() {
+		if err := fn(shard, iterator.shardConn(shard)); err != nil {
+			errs[shard.Key()] = err
+			if !opts.continueOnError {
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// EachShardConnectAsync do the same as EachShardConnect but in parallel every shard.
+//
+// Without ContinueOnErrorOption shards which are not started yet are skipped after the first error
+func EachShardConnectAsync(db DB, fn func(shard ShardConnect, conn DB) error, options ...EachOption) error {
+	iterator, ok := db.(shardIterator)
+	if !ok || !iterator.sharded() {
+		return ErrConnectionIsNotShard
+	}
+
+	opts := newEachOptions(options...)
+
+	var (
+		mx     sync.Mutex
+		errs   = ShardErrors{}
+		failed atomic.Bool
+	)
+
+	wg := errgroup.Group{}
+	if opts.limit > 0 {
+		wg.SetLimit(opts.limit)
+	}
+
+	for _, shard := range iterator.shards() {
+		wg.Go(func() error {
+			if failed.Load() && !opts.continueOnError {
+				return nil
+			}
+
+			if err := fn(shard, iterator.shardConn(shard)); err != nil {
+				failed.Store(true)
+
+				mx.Lock()
+				errs[shard.Key()] = err
+				mx.Unlock()
+			}
+
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func newEachOptions(options ...EachOption) eachOptions {
+	var opts eachOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
@@ -0,0 +1,155 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShardErrors(t *testing.T) {
+	errA := errors.New("a is down")
+	errB := errors.New("b is down")
+	errs := ShardErrors{"b": errB, "a": errA}
+
+	if got, want := errs.Error(), "shard errors: a: a is down; b: b is down"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	var err error = errs
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatal("expected shard errors to unwrap errors of every shard")
+	}
+
+	if errors.Is(err, errors.New("a is down")) {
+		t.Fatal("expected only contained errors to match")
+	}
+}
+
+func TestEachShardConnect(t *testing.T) {
+	errShard := errors.New("shard failed")
+
+	tests := []struct {
+		name     string
+		async    bool
+		options  []EachOption
+		fail     string
+		wantRuns []string
+		wantErrs []string
+	}{
+		{name: "all shards", wantRuns: []string{"a", "b"}},
+		{name: "stop at first error", fail: "a", wantRuns: []string{"a"}, wantErrs: []string{"a"}},
+		{
+			name:     "continue on error",
+			options:  []EachOption{ContinueOnErrorOption()},
+			fail:     "a",
+			wantRuns: []string{"a", "b"},
+			wantErrs: []string{"a"},
+		},
+		{name: "async all shards", async: true, wantRuns: []string{"a", "b"}},
+		{
+			name:     "async stop at first error",
+			async:    true,
+			options:  []EachOption{EachLimitOption(1)},
+			fail:     "a",
+			wantRuns: []string{"a"},
+			wantErrs: []string{"a"},
+		},
+		{
+			name:     "async continue on error",
+			async:    true,
+			options:  []EachOption{ContinueOnErrorOption(), EachLimitOption(1)},
+			fail:     "a",
+			wantRuns: []string{"a", "b"},
+			wantErrs: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mockA, mockB := newScatterShards(t)
+			mocks := map[string]sqlmock.Sqlmock{"a": mockA, "b": mockB}
+			for _, key := range tt.wantRuns {
+				mocks[key].ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			var (
+				mx   sync.Mutex
+				runs []string
+			)
+			fn := func(shard ShardConnect, conn DB) error {
+				mx.Lock()
+				runs = append(runs, shard.Key())
+				mx.Unlock()
+
+				// client of the shard runs query at the shard without selector
+				if _, err := conn.ExecContext(context.Background(), "UPDATE users SET name = 'a'"); err != nil {
+					return err
+				}
+
+				if shard.Key() == tt.fail {
+					return errShard
+				}
+
+				return nil
+			}
+
+			var err error
+			if tt.async {
+				err = EachShardConnectAsync(db, fn, tt.options...)
+			} else {
+				err = EachShardConnect(db, fn, tt.options...)
+			}
+
+			slices.Sort(runs)
+			if !slices.Equal(runs, tt.wantRuns) {
+				t.Fatalf("expected runs at %v, got %v", tt.wantRuns, runs)
+			}
+
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			} else {
+				var errs ShardErrors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected shard errors, got %v", err)
+				}
+
+				for _, key := range tt.wantErrs {
+					if !errors.Is(errs[key], errShard) {
+						t.Fatalf("expected error of shard %s, got %v", key, errs)
+					}
+				}
+
+				if len(errs) != len(tt.wantErrs) {
+					t.Fatalf("expected errors of %v, got %v", tt.wantErrs, errs)
+				}
+			}
+
+			for key, mock := range mocks {
+				if err = mock.ExpectationsWereMet(); err != nil {
+					t.Fatalf("shard %s: %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEachShardConnectNotShard(t *testing.T) {
+	conn, _ := newMock(t)
+	fn := func(ShardConnect, DB) error {
+		return nil
+	}
+
+	if err := EachShardConnect(NewClient(conn), fn); !errors.Is(err, ErrConnectionIsNotShard) {
+		t.Fatalf("expected not shard error, got %v", err)
+	}
+
+	if err := EachShardConnectAsync(NewClient(conn), fn); !errors.Is(err, ErrConnectionIsNotShard) {
+		t.Fatalf("expected not shard error, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/storage"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/sync/errgroup"
+)
+
+// ShardTxOption sets EachShardTx settings
+type ShardTxOption func(options *shardTxOptions)
+
+type shardTxOptions struct {
+	twoPhase  bool
+	decisions storage.DecisionStore
+}
+
+// TwoPhaseCommitOption commits transactions of all shards by PREPARE TRANSACTION & COMMIT PREPARED.
+//
+// Requires max_prepared_transactions > 0 in PostgreSQL settings of every shard.
+// Commit decision is saved to provided store before commit phase,
+// so transactions which failed at commit phase stay prepared and are resolved by RecoverPrepared
+func TwoPhaseCommitOption(decisions storage.DecisionStore) ShardTxOption {
+	return func(options *shardTxOptions) {
+		options.twoPhase = true
+		options.decisions = decisions
+	}
+}
+
+// shardKeyProvider begins transaction at shard with provided key.
+//
+// Transaction is registered as in-flight query of the shard, so shard is not closed until transaction end
+type shardKeyProvider struct {
+	connections *Connections
+	key         string
+}
+
+func (p shardKeyProvider) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	tx, conn, err := p.beginShardTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.release()
+	return tx, nil
+}
+
+func (p shardKeyProvider) beginShardTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, txConn, error) {
+	conn, release, err := p.connections.acquireKey(p.key)
+	if err != nil {
+		return nil, txConn{}, err
+	}
+
+	return beginAcquired(ctx, conn, release, opts)
+}
+
+// shardTx is transaction of single shard started by EachShardTx
+type shardTx struct {
+	shard ShardConnect
+	ctx   context.Context
+}
+
+// EachShardTx begins transaction at every shard and runs fn with every shard one by one.
+//
+// Fn receives context with transaction of the shard, shard connection and client of the shard
+// which uses logger & interceptors of db. Transactions are committed only if fn succeeded for all shards,
+// otherwise all of them are rolled back. Errors are returned as ShardErrors keyed by shard key.
+//
+// By default, transactions are committed one by one and if commit of some shard fails, shards committed before stay committed
+// (transactions of the rest shards are rolled back). Use TwoPhaseCommitOption for atomic commit.
+// Shard removed at runtime is closed only after its transaction is finished.
+// Transaction options can be set by SetTxOptions. Context must not contain transaction
+func EachShardTx(
+	ctx context.Context,
+	db DB,
+	fn func(ctx context.Context, shard ShardConnect, conn DB) error,
+	options ...ShardTxOption,
+) error {
+	iterator, ok := db.(shardIterator)
+	if !ok || !iterator.sharded() {
+		return ErrConnectionIsNotShard
+	}
+
+	if _, ok = GetTx(ctx); ok {
+		return ErrShardTxNested
+	}
+
+	var opts shardTxOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	// transactions of all shards are prepared with identifiers of one global transaction
+	var globalID string
+	if opts.twoPhase {
+		if opts.decisions == nil {
+			return storage.ErrDecisionStoreMissing
+		}
+
+		var err error
+		if ctx, globalID, err = storage.BeginGlobalTx(ctx); err != nil {
+			return err
+		}
+	}
+
+	var txOpts *sql.TxOptions
+	if ctxOpts, ok := GetTxOptions(ctx); ok {
+		txOpts = &ctxOpts
+	}
+
+	// begin transaction at every shard
+	ctx = storage.ResetPropagation(ctx)
+	shards := iterator.shards()
+	transactions := make([]shardTx, 0, len(shards))
+	for _, shard := range shards {
+		// transaction holds the shard, so shard removed concurrently is closed after transaction end
+		txCtx, err := beginScope(ctx, iterator.shardBegin(shard), txOpts, storage.PropagationRequiresNew)
+		if err != nil {
+			rollbackShardTxs(transactions)
+			return ShardErrors{shard.Key(): err}
+		}
+
+		transactions = append(transactions, shardTx{
+			shard: shard,
+			ctx:   txCtx,
+		})
+	}
+
+	// run fn at every shard
+	for _, tx := range transactions {
+		err := errorx.Try(func() error {
+			return fn(tx.ctx, tx.shard, iterator.shardConn(tx.shard))
+		})
+		if err != nil {
+			rollbackShardTxs(transactions)
+			return ShardErrors{tx.shard.Key(): err}
+		}
+	}
+
+	if opts.twoPhase {
+		return commitShardTxsTwoPhase(ctx, transactions, opts.decisions, globalID)
+	}
+
+	return commitShardTxs(transactions)
+}
+
+// commitShardTxs commits transactions one by one. If commit fails, transactions which are not committed yet are rolled back
+func commitShardTxs(transactions []shardTx) error {
+	for idx, tx := range transactions {
+		if err := commitScope(tx.ctx); err != nil {
+			rollbackShardTxs(transactions[idx+1:])
+			return ShardErrors{tx.shard.Key(): err}
+		}
+	}
+
+	return nil
+}
+
+// commitShardTxsTwoPhase prepares all transactions, saves commit decision and commits them only if all of them are prepared.
+//
+// Errors are returned as ShardErrors of failed shards, every error is wrapped by storage.ErrTwoPhasePrepare
+// or storage.ErrTwoPhaseCommit. If commit phase failed, shards missing in errors are committed
+// and decision is kept for recovery of the rest ones.
+// If decision can not be saved, all transactions are rolled back and storage.ErrTwoPhaseDecision is returned
+func commitShardTxsTwoPhase(
+	ctx context.Context,
+	transactions []shardTx,
+	decisions storage.DecisionStore,
+	globalID string,
+) error {
+	// phase 1: prepare
+	if errs := eachShardTx(transactions, prepareScope); len(errs) > 0 {
+		_ = eachShardTx(transactions, rollbackPreparedScope)
+		return errs.wrap(storage.ErrTwoPhasePrepare)
+	}
+
+	// decision may be saved even if error is returned, so it is removed before rollback
+	if err := decisions.SaveCommit(ctx, globalID); err != nil {
+		_ = decisions.Forget(ctx, globalID)
+		_ = eachShardTx(transactions, rollbackPreparedScope)
+		return storage.ErrTwoPhaseDecision.SetError(err)
+	}
+
+	// phase 2: commit prepared
+	if errs := eachShardTx(transactions, commitPreparedScope); len(errs) > 0 {
+		return errs.wrap(storage.ErrTwoPhaseCommit)
+	}
+
+	_ = decisions.Forget(ctx, globalID)
+	return nil
+}
+
+// rollbackShardTxs rolls back provided transactions in parallel
+func rollbackShardTxs(transactions []shardTx) {
+	_ = eachShardTx(transactions, rollbackScope)
+}
+
+// eachShardTx runs fn with context of every transaction in parallel and collects errors
+func eachShardTx(transactions []shardTx, fn func(ctx context.Context) error) ShardErrors {
+	var (
+		mx   sync.Mutex
+		errs = ShardErrors{}
+	)
+
+	wg := errgroup.Group{}
+	for _, tx := range transactions {
+		wg.Go(func() error {
+			if err := fn(tx.ctx); err != nil {
+				mx.Lock()
+				errs[tx.shard.Key()] = err
+				mx.Unlock()
+			}
+
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	return errs
+}
+
+// wrap wraps error of every shard by provided error, so kind of failure can be checked by errors.Is
+func (errs ShardErrors) wrap(wrapper *errorx.Error) ShardErrors {
+	for key, err := range errs {
+		errs[key] = wrapper.SetError(err)
+	}
+
+	return errs
+}
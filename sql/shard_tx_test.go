@@ -0,0 +1,294 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+)
+
+// decisionsStub is in-memory storage.DecisionStore which fails with configured errors
+type decisionsStub struct {
+	err      error
+	checkErr error
+	saved    map[string]bool
+}
+
+func (d *decisionsStub) SaveCommit(ctx context.Context, globalID string) error {
+	d.saved[globalID] = true
+	return d.err
+}
+
+func (d *decisionsStub) IsCommitted(ctx context.Context, globalID string) (bool, error) {
+	return d.saved[globalID], d.checkErr
+}
+
+func (d *decisionsStub) Forget(ctx context.Context, globalID string) error {
+	delete(d.saved, globalID)
+	return nil
+}
+
+func TestEachShardTx(t *testing.T) {
+	errFn := errors.New("fn failed")
+	errConn := errors.New("connection lost")
+
+	// prepared transactions of all shards belong to one global transaction
+	const (
+		prepare  = "PREPARE TRANSACTION 'storage_[0-9a-f]+_[12]'"
+		commit   = "COMMIT PREPARED 'storage_[0-9a-f]+_[12]'"
+		rollback = "ROLLBACK PREPARED 'storage_[0-9a-f]+_[12]'"
+	)
+
+	tests := []struct {
+		name      string
+		twoPhase  bool
+		decideErr error
+		// failShard is key of shard where fn fails
+		failShard string
+		expect    func(a, b sqlmock.Sqlmock)
+		wantErr   error
+		// wantShards are keys of shards returned in ShardErrors
+		wantShards []string
+		// wantDecision is true if commit decision must be kept for recovery
+		wantDecision bool
+	}{
+		{
+			name: "commit",
+			expect: func(a, b sqlmock.Sqlmock) {
+				for _, mock := range []sqlmock.Sqlmock{a, b} {
+					mock.ExpectBegin()
+					mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				}
+			},
+		},
+		{
+			name: "begin failed",
+			expect: func(a, b sqlmock.Sqlmock) {
+				a.ExpectBegin()
+				a.ExpectRollback()
+				b.ExpectBegin().WillReturnError(errConn)
+			},
+			wantErr:    errConn,
+			wantShards: []string{"b"},
+		},
+		{
+			name:      "fn failed",
+			failShard: "b",
+			expect: func(a, b sqlmock.Sqlmock) {
+				a.ExpectBegin()
+				a.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				a.ExpectRollback()
+				b.ExpectBegin()
+				b.ExpectRollback()
+			},
+			wantErr:    errFn,
+			wantShards: []string{"b"},
+		},
+		{
+			name: "commit failed",
+			expect: func(a, b sqlmock.Sqlmock) {
+				a.ExpectBegin()
+				a.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				a.ExpectCommit().WillReturnError(errConn)
+				b.ExpectBegin()
+				b.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				b.ExpectRollback()
+			},
+			wantErr:    errConn,
+			wantShards: []string{"a"},
+		},
+		{
+			name:     "two-phase commit",
+			twoPhase: true,
+			expect: func(a, b sqlmock.Sqlmock) {
+				for _, mock := range []sqlmock.Sqlmock{a, b} {
+					mock.ExpectBegin()
+					mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(prepare).WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectRollback()
+					mock.ExpectExec(commit).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			},
+		},
+		{
+			name:     "two-phase prepare failed",
+			twoPhase: true,
+			expect: func(a, b sqlmock.Sqlmock) {
+				a.ExpectBegin()
+				a.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				a.ExpectExec(prepare).WillReturnResult(sqlmock.NewResult(0, 0))
+				a.ExpectRollback()
+				a.ExpectExec(rollback).WillReturnResult(sqlmock.NewResult(0, 0))
+				b.ExpectBegin()
+				b.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				b.ExpectExec(prepare).WillReturnError(errConn)
+				b.ExpectRollback()
+			},
+			wantErr:    storage.ErrTwoPhasePrepare,
+			wantShards: []string{"b"},
+		},
+		{
+			name:     "two-phase commit partially failed",
+			twoPhase: true,
+			expect: func(a, b sqlmock.Sqlmock) {
+				a.ExpectBegin()
+				a.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				a.ExpectExec(prepare).WillReturnResult(sqlmock.NewResult(0, 0))
+				a.ExpectRollback()
+				a.ExpectExec(commit).WillReturnResult(sqlmock.NewResult(0, 0))
+				b.ExpectBegin()
+				b.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				b.ExpectExec(prepare).WillReturnResult(sqlmock.NewResult(0, 0))
+				b.ExpectRollback()
+				b.ExpectExec(commit).WillReturnError(errConn)
+			},
+			wantErr:      storage.ErrTwoPhaseCommit,
+			wantShards:   []string{"b"},
+			wantDecision: true,
+		},
+		{
+			name:      "two-phase decision not saved",
+			twoPhase:  true,
+			decideErr: errConn,
+			expect: func(a, b sqlmock.Sqlmock) {
+				for _, mock := range []sqlmock.Sqlmock{a, b} {
+					mock.ExpectBegin()
+					mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(prepare).WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectRollback()
+					mock.ExpectExec(rollback).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			},
+			wantErr: storage.ErrTwoPhaseDecision,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mockA, mockB := newScatterShards(t)
+			tt.expect(mockA, mockB)
+
+			decisions := &decisionsStub{err: tt.decideErr, saved: make(map[string]bool)}
+			var options []ShardTxOption
+			if tt.twoPhase {
+				options = append(options, TwoPhaseCommitOption(decisions))
+			}
+
+			err := EachShardTx(context.Background(), db, func(ctx context.Context, shard ShardConnect, conn DB) error {
+				if shard.Key() == tt.failShard {
+					return errFn
+				}
+
+				_, err := conn.ExecContext(ctx, "UPDATE users SET name = 'a'")
+				return err
+			}, options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if saved := len(decisions.saved) > 0; saved != tt.wantDecision {
+				t.Fatalf("expected decision kept %v, got %v", tt.wantDecision, saved)
+			}
+
+			if tt.wantShards != nil {
+				var errs ShardErrors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected shard errors, got %v", err)
+				}
+
+				if shards := slices.Sorted(maps.Keys(errs)); !slices.Equal(shards, tt.wantShards) {
+					t.Fatalf("expected errors of shards %v, got %v", tt.wantShards, shards)
+				}
+			}
+
+			if err = mockA.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if err = mockB.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestEachShardTxHoldsShard(t *testing.T) {
+	conn, mock := newMock(t)
+	connections := newConnections([]ShardConnect{newShardConnect("a", nil, conn)}, LookupSelector(nil))
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectClose()
+
+	removed := make(chan error, 1)
+	err := EachShardTx(context.Background(), NewClientShard(connections), func(ctx context.Context, shard ShardConnect, conn DB) error {
+		go func() {
+			removed <- connections.Remove(context.Background(), shard.Key())
+		}()
+
+		select {
+		case <-removed:
+			return errors.New("shard is removed before commit")
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		}
+	})
+	if err != nil {
+		t.Fatalf("each shard tx: %v", err)
+	}
+
+	if err = <-removed; err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEachShardTxNotAllowed(t *testing.T) {
+	conn, mock := newMock(t)
+	shards, _, _ := newScatterShards(t)
+	client := shards.(*clientShard)
+
+	mock.ExpectBegin()
+	tx, err := conn.Beginx()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		db      DB
+		options []ShardTxOption
+		wantErr error
+	}{
+		{name: "not shard client", ctx: context.Background(), db: NewClient(conn), wantErr: ErrConnectionIsNotShard},
+		{name: "single shard client", ctx: context.Background(), db: client.shardConn(client.shards()[0]), wantErr: ErrConnectionIsNotShard},
+		{name: "inside transaction", ctx: SetTx(context.Background(), tx), db: shards, wantErr: ErrShardTxNested},
+		{
+			name:    "two-phase without decision store",
+			ctx:     context.Background(),
+			db:      shards,
+			options: []ShardTxOption{TwoPhaseCommitOption(nil)},
+			wantErr: storage.ErrDecisionStoreMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := EachShardTx(tt.ctx, tt.db, func(ctx context.Context, shard ShardConnect, conn DB) error {
+				return nil
+			}, tt.options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/boostgo/convert"
+	"github.com/boostgo/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// SlowQuery describes query which duration exceeded threshold
+type SlowQuery struct {
+	QueryLog
+	Threshold time.Duration
+	// Plan is result of "EXPLAIN (FORMAT JSON)" of the query. Empty if plan was not captured
+	Plan json.RawMessage
+}
+
+// SlowQueryReporter receives queries which duration exceeded threshold
+type SlowQueryReporter func(ctx context.Context, query SlowQuery)
+
+// SlowQueryOption enables reporting queries which run longer than threshold.
+//
+// If reporter is nil, slow queries are printed by global logger with "warn" level
+func SlowQueryOption(threshold time.Duration, reporter SlowQueryReporter) ClientOption {
+	return func(options *clientOptions) {
+		if options.slowQuery == nil {
+			options.slowQuery = &slowQuery{}
+		}
+
+		options.slowQuery.threshold = threshold
+		options.slowQuery.reporter = reporter
+		if reporter == nil {
+			options.slowQuery.reporter = printSlowQuery
+		}
+	}
+}
+
+// ExplainOption enables capturing plan of slow queries by "EXPLAIN (FORMAT JSON)".
+//
+// Plan is captured not often than once per interval, so slow database is not loaded by explains of every slow query.
+// EXPLAIN runs on any connection of the pool, not on the one which ran the query: session settings (SET, temporary tables)
+// of the query connection are not visible there, so plan may differ from the executed one or be missing.
+// Only single SELECT, INSERT, UPDATE, DELETE & WITH statements called outside of transaction are explained:
+// failed EXPLAIN inside transaction would abort it.
+// Works only with SlowQueryOption
+func ExplainOption(interval time.Duration) ClientOption {
+	return func(options *clientOptions) {
+		if options.slowQuery == nil {
+			options.slowQuery = &slowQuery{}
+		}
+
+		options.slowQuery.explain = true
+		options.slowQuery.explainInterval = interval
+	}
+}
+
+type slowQuery struct {
+	threshold       time.Duration
+	reporter        SlowQueryReporter
+	explain         bool
+	explainInterval time.Duration
+	// lastExplain is unix nano time of last captured plan
+	lastExplain atomic.Int64
+}
+
+// report calls reporter if query is slow
+func (s *slowQuery) report(ctx context.Context, exec queryExecutor, entry QueryLog) {
+	if s == nil || s.reporter == nil || s.threshold <= 0 || entry.Duration < s.threshold {
+		return
+	}
+
+	report := SlowQuery{
+		QueryLog:  entry,
+		Threshold: s.threshold,
+	}
+
+	if entry.Err == nil && s.allowExplain() {
+		report.Plan = explainQuery(ctx, exec, entry)
+	}
+
+	s.reporter(ctx, report)
+}
+
+// allowExplain checks if interval after last explain is passed and reserves explain
+func (s *slowQuery) allowExplain() bool {
+	if !s.explain {
+		return false
+	}
+
+	now := time.Now().UnixNano()
+	last := s.lastExplain.Load()
+	if last != 0 && now-last < s.explainInterval.Nanoseconds() {
+		return false
+	}
+
+	return s.lastExplain.CompareAndSwap(last, now)
+}
+
+// explainQuery returns plan of query. Only queries with known arguments can be explained.
+//
+// Plan is captured by connection of the pool which is free now, see ExplainOption
+func explainQuery(ctx context.Context, exec queryExecutor, entry QueryLog) json.RawMessage {
+	if _, ok := exec.(*sqlx.Tx); ok || !explainable(entry.Query) {
+		return nil
+	}
+
+	query, args := entry.Query, entry.Args
+
+	switch entry.QueryType {
+	case "ExecContext", "SelectContext", "GetContext", "QueryContext", "QueryxContext", "QueryRowxContext":
+	case "NamedExecContext":
+		if len(args) != 1 {
+			return nil
+		}
+
+		binder, ok := exec.(executor)
+		if !ok {
+			return nil
+		}
+
+		var err error
+		query, args, err = binder.BindNamed(query, args[0])
+		if err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	var plan []byte
+	if err := exec.QueryRowxContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan); err != nil {
+		return nil
+	}
+
+	return plan
+}
+
+// explainable checks if statement can be explained by EXPLAIN.
+//
+// Multi-statement queries are not explained: query without arguments is sent by simple protocol,
+// so statements after the first one would be run by EXPLAIN for real
+func explainable(query string) bool {
+	if strings.Contains(strings.TrimRight(query, "; \t\r\n"), ";") {
+		return false
+	}
+
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	default:
+		return false
+	}
+}
+
+func printSlowQuery(ctx context.Context, query SlowQuery) {
+	convertedArgs := make([]string, 0, len(query.Args))
+	for _, arg := range query.Args {
+		convertedArgs = append(convertedArgs, convert.String(arg))
+	}
+
+	event := log.
+		Warn().
+		Ctx(ctx).
+		Str("query_type", query.QueryType).
+		Str("query", query.Query).
+		Strs("args", convertedArgs).
+		Duration("duration", query.Duration).
+		Duration("threshold", query.Threshold)
+
+	if query.Key != "" {
+		event = event.Str("connection_key", query.Key)
+	}
+
+	if len(query.Plan) > 0 {
+		event = event.Str("plan", string(query.Plan))
+	}
+
+	event.Msg("Slow query")
+}
@@ -0,0 +1,100 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExplainable(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "SELECT * FROM users", want: true},
+		{query: "\n  select id from users", want: true},
+		{query: "INSERT INTO users (id) VALUES ($1)", want: true},
+		{query: "UPDATE users SET name = $1", want: true},
+		{query: "DELETE FROM users", want: true},
+		{query: "WITH ids AS (SELECT 1) SELECT * FROM ids", want: true},
+		{query: "VACUUM users", want: false},
+		{query: "CREATE INDEX users_name_idx ON users (name)", want: false},
+		{query: "SET search_path = public", want: false},
+		{query: "SELECT * FROM users;\n", want: true},
+		{query: "SELECT * FROM users; DELETE FROM users", want: false},
+		{query: "UPDATE users SET name = 'a';DROP TABLE users;", want: false},
+		{query: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := explainable(tt.query); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSlowQueryExplain(t *testing.T) {
+	plan := `[{"Plan": {}}]`
+
+	tests := []struct {
+		name     string
+		query    string
+		inTx     bool
+		wantPlan bool
+	}{
+		{name: "explained", query: "UPDATE users SET name = 'a'", wantPlan: true},
+		{name: "not explainable statement", query: "VACUUM users"},
+		{name: "inside transaction", query: "UPDATE users SET name = 'a'", inTx: true},
+		{name: "multi-statement query", query: "UPDATE users SET name = 'a'; DELETE FROM users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+
+			var reports []SlowQuery
+			client := NewClient(conn,
+				SlowQueryOption(time.Nanosecond, func(ctx context.Context, query SlowQuery) {
+					reports = append(reports, query)
+				}),
+				ExplainOption(0),
+			)
+
+			ctx := context.Background()
+			if tt.inTx {
+				mock.ExpectBegin()
+				tx, err := conn.Beginx()
+				if err != nil {
+					t.Fatalf("begin: %v", err)
+				}
+				ctx = SetTx(ctx, tx)
+			}
+
+			mock.ExpectExec(tt.query).WillReturnResult(sqlmock.NewResult(0, 1))
+			if tt.wantPlan {
+				mock.ExpectQuery("EXPLAIN \\(FORMAT JSON\\) " + tt.query).
+					WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow([]byte(plan)))
+			}
+
+			if _, err := client.ExecContext(ctx, tt.query); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+
+			if len(reports) != 1 {
+				t.Fatalf("expected 1 slow query report, got %d", len(reports))
+			}
+
+			if gotPlan := len(reports[0].Plan) > 0; gotPlan != tt.wantPlan {
+				t.Fatalf("expected plan: %v, got %s", tt.wantPlan, reports[0].Plan)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 // Package sql provide tools for manipulating connections and helper tools (sqlx extension).
 // Features:
-// - Client for single database and for sharding (common interface - DB).
+// - Client for single database, for sharding and for primary with read replicas (common interface - DB).
 // - More simple connecting.
 // - Arguments tool. Helps to set arguments for multiple insert.
 // - Connection builder. Building connection string or connecting.
 // - Any driver support.
 // - Migrations.
 // - Transactor implementation. Implementation based on manipulating transaction from context.
+// - Nested transactions (savepoints) & retry of serialization failures.
+// - Transactional outbox with relay worker.
+// - Query logger & slow queries reporting with plan capture.
+// - Interceptors of DB methods (Intercept & InterceptorOption).
+// - Runtime add, remove, replace & reload of shards.
+// - Transactions across all shards with optional two-phase commit (EachShardTx).
 package sql
@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/boostgo/storage"
+)
+
+// TraceInterceptor starts span of query call by tracer set by storage.SetTracer.
+//
+// Clients created by NewClient & NewClientShard use it by default
+func TraceInterceptor(ctx context.Context, call *QueryCall, next Handler) error {
+	_, inTx := GetTx(ctx)
+
+	ctx, span := storage.StartQuerySpan(ctx, storage.QuerySpan{
+		System:      driverName(call.exec),
+		Operation:   call.Method,
+		Statement:   call.Query,
+		ShardKey:    call.Key,
+		Transaction: inTx,
+	})
+	defer span.End()
+
+	err := next(ctx, call)
+	if err != nil && !NotFound(err) {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// driverName returns name of driver used by connection or transaction
+func driverName(exec queryExecutor) string {
+	driver, ok := exec.(interface{ DriverName() string })
+	if !ok {
+		return "sql"
+	}
+
+	return driver.DriverName()
+}
@@ -2,12 +2,22 @@ package sql
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/boostgo/errorx"
+	"github.com/boostgo/storage"
 	"github.com/jmoiron/sqlx"
 )
 
-const transactionKey = "storage_sql_tx"
+const (
+	transactionKey = "storage_sql_tx"
+	txScopeKey     = "storage_sql_tx_scope"
+	txOptionsKey   = "storage_sql_tx_options"
+	txShardKey     = "storage_sql_tx_shard"
+	txCancelKey    = "storage_sql_tx_cancelable"
+)
 
 // SetTx sets transaction key to new context
 func SetTx(ctx context.Context, tx *sqlx.Tx) context.Context {
@@ -25,6 +35,68 @@ func GetTx(ctx context.Context) (*sqlx.Tx, bool) {
 	return tx, ok
 }
 
+// txShard is key of shard where transaction is started
+type txShard struct {
+	tx  *sqlx.Tx
+	key string
+}
+
+// SetTxShard sets to new context key of shard where transaction is started.
+//
+// Transactor of Connections sets it automatically. Needed only if transaction is set to context by SetTx
+func SetTxShard(ctx context.Context, tx *sqlx.Tx, key string) context.Context {
+	return context.WithValue(ctx, txShardKey, txShard{
+		tx:  tx,
+		key: key,
+	})
+}
+
+// GetTxShard returns key of shard where transaction from context is started if it is known
+func GetTxShard(ctx context.Context) (string, bool) {
+	tx, ok := GetTx(ctx)
+	if !ok {
+		return "", false
+	}
+
+	shard, ok := ctx.Value(txShardKey).(txShard)
+	if !ok || shard.tx != tx {
+		return "", false
+	}
+
+	return shard.key, true
+}
+
+// SetTxOptions sets transaction options to new context.
+//
+// Options are merged over default options of transactor field by field: isolation level is overridden if it is set
+// and read only mode is turned on if it is set (it can not be turned off by context).
+// Options are used by the next transaction started with this context.
+// Nested transactions (savepoints) ignore options because they use options of outer transaction
+func SetTxOptions(ctx context.Context, opts sql.TxOptions) context.Context {
+	return context.WithValue(ctx, txOptionsKey, opts)
+}
+
+// GetTxOptions returns transaction options from context if they exist
+func GetTxOptions(ctx context.Context) (sql.TxOptions, bool) {
+	opts, ok := ctx.Value(txOptionsKey).(sql.TxOptions)
+	return opts, ok
+}
+
+// CancelableTx marks context, so transaction started by Atomic with this context is bound to it:
+// database/sql rolls transaction back as soon as context is cancelled.
+//
+// By default, transaction of Atomic is not bound to context (as it was with Beginx)
+// and it is rolled back by Atomic itself after fn returned
+func CancelableTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txCancelKey, true)
+}
+
+// IsCancelableTx checks if context is marked by CancelableTx
+func IsCancelableTx(ctx context.Context) bool {
+	cancelable, _ := ctx.Value(txCancelKey).(bool)
+	return cancelable
+}
+
 // Transaction run "actions" by created transaction object
 func Transaction(conn *sqlx.DB, transactionActions func(tx *sqlx.Tx) error) error {
 	transaction, err := conn.Beginx()
@@ -42,33 +114,333 @@ func Transaction(conn *sqlx.DB, transactionActions func(tx *sqlx.Tx) error) erro
 	return transaction.Commit()
 }
 
-func Atomic(ctx context.Context, conn *sqlx.DB, fn func(ctx context.Context) error) error {
-	var tx *sqlx.Tx
-	var ok bool
-	var err error
+// Atomic runs fn inside transaction.
+//
+// If context already contain transaction, fn runs inside SAVEPOINT of this transaction,
+// so error of fn rolls back only changes made by fn and outer transaction stays alive.
+// This behavior can be changed by storage.SetPropagation.
+//
+// New transaction uses options set by SetTxOptions if context contain them.
+// New transaction is not rolled back by cancellation of context unless context is marked by CancelableTx
+func Atomic(ctx context.Context, conn *sqlx.DB, fn func(ctx context.Context) error) (err error) {
+	var opts *sql.TxOptions
+	if ctxOpts, ok := GetTxOptions(ctx); ok {
+		opts = &ctxOpts
+	}
 
-	tx, ok = GetTx(ctx)
-	if ok {
-		return errorx.Try(func() error {
-			return fn(ctx)
-		})
+	var provider TransactorConnectionProvider = detachedProvider{conn: conn}
+	if IsCancelableTx(ctx) {
+		provider = conn
 	}
 
-	tx, err = conn.Beginx()
+	ctx, err = beginScope(ctx, provider, opts, storage.PropagationNested)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if err != nil {
-			_ = tx.Rollback()
+			_ = rollbackScope(ctx)
 			return
 		}
 
-		_ = tx.Commit()
+		err = commitScope(ctx)
 	}()
 
 	return errorx.Try(func() error {
-		return fn(context.WithValue(ctx, transactionKey, tx))
+		return fn(ctx)
 	})
 }
+
+// detachedProvider begins transaction which is not bound to context cancellation
+type detachedProvider struct {
+	conn *sqlx.DB
+}
+
+func (p detachedProvider) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return p.conn.BeginTxx(context.WithoutCancel(ctx), opts)
+}
+
+// txScope describes part of transaction started by single BeginCtx or Atomic call
+type txScope struct {
+	tx *sqlx.Tx
+	// owner means scope started transaction and responsible to commit or rollback it
+	owner bool
+	// savepoint is not empty if scope is nested transaction
+	savepoint string
+	depth     int
+	// state is shared between all scopes of the same transaction
+	state *txState
+	// hooks are run by owner scope after transaction finish.
+	// Nested scope drops commit hooks registered after hooksMark if it is rolled back
+	hooks     *storage.Hooks
+	hooksMark int
+	// gid is global identifier of transaction if it is prepared by two-phase commit
+	gid string
+	// db is connection pool of transaction, prepared transaction is finished by its dedicated connection
+	db *sqlx.DB
+	// release finishes in-flight query of shard where transaction began
+	release func()
+}
+
+// finish is called by owner scope when transaction is committed or rolled back,
+// so shard where transaction began can be removed after it
+func (s *txScope) finish() {
+	if s.release == nil {
+		return
+	}
+
+	s.release()
+	s.release = nil
+}
+
+// txState is shared state of transaction and all scopes which joined it
+type txState struct {
+	rollbackOnly atomic.Bool
+}
+
+// getScope returns scope from context if it exist and belongs to provided transaction
+func getScope(ctx context.Context, tx *sqlx.Tx) (*txScope, bool) {
+	scope, ok := ctx.Value(txScopeKey).(*txScope)
+	if !ok || scope.tx != tx {
+		return nil, false
+	}
+
+	return scope, true
+}
+
+// beginScope begins transaction scope by provided propagation.
+//
+// If context contain propagation set by storage.SetPropagation, it overrides provided one
+func beginScope(
+	ctx context.Context,
+	provider TransactorConnectionProvider,
+	opts *sql.TxOptions,
+	propagation storage.Propagation,
+) (context.Context, error) {
+	if ctxPropagation := storage.GetPropagation(ctx); ctxPropagation != storage.PropagationDefault {
+		propagation = ctxPropagation
+	}
+	ctx = storage.ResetPropagation(ctx)
+
+	tx, exist := GetTx(ctx)
+	parent, _ := getScope(ctx, tx)
+
+	switch propagation {
+	case storage.PropagationRequired:
+		if exist {
+			return joinScope(ctx, tx, parent), nil
+		}
+	case storage.PropagationSupports:
+		if exist {
+			return joinScope(ctx, tx, parent), nil
+		}
+
+		return ctx, nil
+	case storage.PropagationMandatory:
+		if !exist {
+			return ctx, storage.ErrTransactionRequired
+		}
+
+		return joinScope(ctx, tx, parent), nil
+	case storage.PropagationNever:
+		if exist {
+			return ctx, storage.ErrTransactionNotAllowed
+		}
+
+		return ctx, nil
+	case storage.PropagationRequiresNew:
+	default:
+		if exist {
+			return beginSavepoint(ctx, tx, parent)
+		}
+	}
+
+	newTx, conn, err := beginTx(ctx, provider, opts)
+	if err != nil {
+		return ctx, ErrTransactorBegin.SetError(err)
+	}
+
+	if conn.shard != "" {
+		ctx = SetTxShard(ctx, newTx, conn.shard)
+	}
+
+	ctx, hooks := storage.BeginHooks(ctx)
+	return context.WithValue(SetTx(ctx, newTx), txScopeKey, &txScope{
+		tx:      newTx,
+		owner:   true,
+		state:   &txState{},
+		hooks:   hooks,
+		db:      conn.db,
+		release: conn.release,
+	}), nil
+}
+
+// txConn describes connection where transaction began
+type txConn struct {
+	// db is connection pool of transaction if it is known. Used to finish prepared transaction
+	db *sqlx.DB
+	// shard is key of shard if transaction began at shard connection
+	shard string
+	// release finishes in-flight query of the shard, so removed shard can be closed. Nil if there is no shard
+	release func()
+}
+
+// shardTxProvider is implemented by providers which choose shard to begin transaction
+type shardTxProvider interface {
+	beginShardTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, txConn, error)
+}
+
+// beginTx begins transaction by provider and returns connection where it began
+func beginTx(ctx context.Context, provider TransactorConnectionProvider, opts *sql.TxOptions) (*sqlx.Tx, txConn, error) {
+	switch p := provider.(type) {
+	case shardTxProvider:
+		return p.beginShardTx(ctx, opts)
+	case *sqlx.DB:
+		tx, err := p.BeginTxx(ctx, opts)
+		return tx, txConn{db: p}, err
+	case detachedProvider:
+		tx, err := p.BeginTxx(ctx, opts)
+		return tx, txConn{db: p.conn}, err
+	}
+
+	tx, err := provider.BeginTxx(ctx, opts)
+	return tx, txConn{}, err
+}
+
+// joinScope creates scope which participates in existing transaction
+func joinScope(ctx context.Context, tx *sqlx.Tx, parent *txScope) context.Context {
+	scope := &txScope{
+		tx: tx,
+	}
+
+	if parent != nil {
+		scope.depth = parent.depth
+		scope.state = parent.state
+	}
+
+	return context.WithValue(ctx, txScopeKey, scope)
+}
+
+// beginSavepoint creates SAVEPOINT inside provided transaction and sets scope to new context.
+//
+// Savepoint names depend on nesting depth, so every nested level has its own name
+func beginSavepoint(ctx context.Context, tx *sqlx.Tx, parent *txScope) (context.Context, error) {
+	scope := &txScope{
+		tx:    tx,
+		depth: 1,
+	}
+
+	if parent != nil {
+		scope.depth = parent.depth + 1
+		scope.state = parent.state
+	}
+
+	if hooks, ok := storage.GetHooks(ctx); ok {
+		scope.hooks = hooks
+		scope.hooksMark = hooks.Mark()
+	}
+
+	scope.savepoint = "storage_sp_" + strconv.Itoa(scope.depth)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+scope.savepoint); err != nil {
+		return ctx, ErrTransactorSavepoint.SetError(err)
+	}
+
+	return context.WithValue(ctx, txScopeKey, scope), nil
+}
+
+// commitScope finishes scope from context successfully.
+//
+// Transaction is committed only by scope which started it, then commit hooks run.
+// Nested scope releases its savepoint.
+// Joined scope does nothing because transaction is committed by its owner
+func commitScope(ctx context.Context) error {
+	tx, ok := GetTx(ctx)
+	if !ok {
+		return nil
+	}
+
+	scope, ok := getScope(ctx, tx)
+	if !ok {
+		// transaction was set to context by SetTx
+		if err := tx.Commit(); err != nil {
+			return ErrTransactorCommit.SetError(err)
+		}
+
+		return nil
+	}
+
+	switch {
+	case scope.savepoint != "":
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+scope.savepoint); err != nil {
+			return ErrTransactorCommit.
+				SetError(err).
+				AddParam("savepoint", scope.savepoint)
+		}
+	case scope.owner:
+		defer scope.finish()
+		if scope.state.rollbackOnly.Load() {
+			defer scope.hooks.RunRollback()
+			if err := tx.Rollback(); err != nil {
+				return ErrTransactorRollback.SetError(err)
+			}
+
+			return storage.ErrTransactionRollbackOnly
+		}
+
+		if err := tx.Commit(); err != nil {
+			scope.hooks.RunRollback()
+			return ErrTransactorCommit.SetError(err)
+		}
+
+		scope.hooks.RunCommit()
+	}
+
+	return nil
+}
+
+// rollbackScope finishes scope from context with rollback.
+//
+// Transaction is rolled back only by scope which started it, then rollback hooks run.
+// Nested scope rolls back to its savepoint and drops commit hooks registered inside it.
+// Joined scope marks transaction as "rollback only", so owner rolls it back instead of commit.
+// If joined transaction was set by SetTx, its owner is unknown and storage.ErrTransactionNotOwned is returned
+// without rolling transaction back
+func rollbackScope(ctx context.Context) error {
+	tx, ok := GetTx(ctx)
+	if !ok {
+		return nil
+	}
+
+	scope, ok := getScope(ctx, tx)
+	switch {
+	case ok && scope.savepoint != "":
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+scope.savepoint); err != nil {
+			return ErrTransactorRollback.
+				SetError(err).
+				AddParam("savepoint", scope.savepoint)
+		}
+
+		scope.hooks.DiscardFrom(scope.hooksMark)
+	case ok && scope.owner:
+		defer scope.finish()
+		defer scope.hooks.RunRollback()
+		if err := tx.Rollback(); err != nil {
+			return ErrTransactorRollback.SetError(err)
+		}
+	case ok:
+		// joined scope never ends transaction it does not own
+		if scope.state == nil {
+			return storage.ErrTransactionNotOwned
+		}
+
+		scope.state.rollbackOnly.Store(true)
+	default:
+		// transaction set by SetTx has no scope which owns it, so it is rolled back right here
+		if err := tx.Rollback(); err != nil {
+			return ErrTransactorRollback.SetError(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/boostgo/storage"
+)
+
+func TestTransactorPropagation(t *testing.T) {
+	tests := []struct {
+		name        string
+		propagation storage.Propagation
+		outer       bool
+		expect      func(mock sqlmock.Sqlmock)
+		wantTx      bool
+		wantErr     error
+	}{
+		{
+			name:        "required creates transaction",
+			propagation: storage.PropagationRequired,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+			},
+			wantTx: true,
+		},
+		{
+			name:        "required joins transaction",
+			propagation: storage.PropagationRequired,
+			outer:       true,
+			wantTx:      true,
+		},
+		{
+			name:        "requires new begins independent transaction",
+			propagation: storage.PropagationRequiresNew,
+			outer:       true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+			},
+			wantTx: true,
+		},
+		{
+			name:        "supports without transaction",
+			propagation: storage.PropagationSupports,
+		},
+		{
+			name:        "mandatory without transaction",
+			propagation: storage.PropagationMandatory,
+			wantErr:     storage.ErrTransactionRequired,
+		},
+		{
+			name:        "never inside transaction",
+			propagation: storage.PropagationNever,
+			outer:       true,
+			wantErr:     storage.ErrTransactionNotAllowed,
+		},
+		{
+			name:        "nested creates savepoint",
+			propagation: storage.PropagationNested,
+			outer:       true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantTx: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			transactor := NewTransactor(conn)
+
+			ctx := context.Background()
+			if tt.outer {
+				mock.ExpectBegin()
+				var err error
+				ctx, err = transactor.BeginCtx(ctx)
+				if err != nil {
+					t.Fatalf("begin outer transaction: %v", err)
+				}
+			}
+
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			txCtx, err := transactor.BeginCtx(storage.SetPropagation(ctx, tt.propagation))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && transactor.IsTx(txCtx) != tt.wantTx {
+				t.Fatalf("expected transaction in context: %v", tt.wantTx)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestJoinedRollbackMarksRollbackOnly(t *testing.T) {
+	conn, mock := newMock(t)
+	transactor := NewTransactor(conn, PropagationOption(storage.PropagationRequired))
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	ctx, err := transactor.BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	joined, err := transactor.BeginCtx(ctx)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if err = transactor.RollbackCtx(joined); err != nil {
+		t.Fatalf("rollback joined: %v", err)
+	}
+
+	if err = transactor.CommitCtx(ctx); !errors.Is(err, storage.ErrTransactionRollbackOnly) {
+		t.Fatalf("expected rollback only error, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJoinedRollbackDoesNotEndForeignTransaction(t *testing.T) {
+	conn, mock := newMock(t)
+	transactor := NewTransactor(conn, PropagationOption(storage.PropagationRequired))
+
+	mock.ExpectBegin()
+	tx, err := conn.Beginx()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	joined, err := transactor.BeginCtx(SetTx(context.Background(), tx))
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if err = transactor.RollbackCtx(joined); !errors.Is(err, storage.ErrTransactionNotOwned) {
+		t.Fatalf("expected not owned error, got %v", err)
+	}
+
+	// transaction is still alive and can be committed by its owner
+	mock.ExpectCommit()
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("commit by owner: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
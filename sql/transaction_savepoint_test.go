@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func newMock(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
+
+func TestAtomicSavepoints(t *testing.T) {
+	errInner := errors.New("inner")
+	errOuter := errors.New("outer")
+
+	tests := []struct {
+		name     string
+		innerErr error
+		outerErr error
+		expect   func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "nested success releases savepoint",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("RELEASE SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:     "nested error rolls back only savepoint",
+			innerErr: errInner,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ROLLBACK TO SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:     "outer error rolls back transaction",
+			outerErr: errOuter,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("RELEASE SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			tt.expect(mock)
+
+			err := Atomic(context.Background(), conn, func(ctx context.Context) error {
+				innerErr := Atomic(ctx, conn, func(ctx context.Context) error {
+					return tt.innerErr
+				})
+				if !errors.Is(innerErr, tt.innerErr) {
+					t.Fatalf("expected inner error %v, got %v", tt.innerErr, innerErr)
+				}
+
+				return tt.outerErr
+			})
+			if !errors.Is(err, tt.outerErr) {
+				t.Fatalf("expected outer error %v, got %v", tt.outerErr, err)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAtomicSavepointDepth(t *testing.T) {
+	conn, mock := newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT storage_sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT storage_sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT storage_sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := Atomic(context.Background(), conn, func(ctx context.Context) error {
+		return Atomic(ctx, conn, func(ctx context.Context) error {
+			return Atomic(ctx, conn, func(ctx context.Context) error {
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
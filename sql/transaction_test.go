@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestAtomicCancelledContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancelable bool
+		wantErr    error
+	}{
+		{name: "detached by default", cancelable: false},
+		{name: "bound by CancelableTx", cancelable: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock := newMock(t)
+			mock.ExpectBegin()
+			if tt.wantErr == nil {
+				mock.ExpectCommit()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelable {
+				ctx = CancelableTx(ctx)
+			}
+
+			err := Atomic(ctx, conn, func(ctx context.Context) error {
+				cancel()
+				return nil
+			})
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if err = mock.ExpectationsWereMet(); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTransactorTxOptions(t *testing.T) {
+	serializable := sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	tests := []struct {
+		name    string
+		options []TransactorOption
+		ctx     context.Context
+		want    sql.TxOptions
+	}{
+		{
+			name: "defaults",
+			ctx:  context.Background(),
+			want: sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		},
+		{
+			name:    "transactor options",
+			options: []TransactorOption{IsolationOption(sql.LevelRepeatableRead), ReadOnlyOption(true)},
+			ctx:     context.Background(),
+			want:    sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		},
+		{
+			name:    "context overrides isolation and keeps read only",
+			options: []TransactorOption{ReadOnlyOption(true)},
+			ctx:     SetTxOptions(context.Background(), serializable),
+			want:    sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true},
+		},
+		{
+			name:    "context turns read only on and keeps isolation",
+			options: []TransactorOption{IsolationOption(sql.LevelRepeatableRead)},
+			ctx:     SetTxOptions(context.Background(), sql.TxOptions{ReadOnly: true}),
+			want:    sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactor := NewTransactor(nil, tt.options...).(*sqlTransactor)
+			if got := *transactor.txOptions(tt.ctx); got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
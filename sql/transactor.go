@@ -13,14 +13,61 @@ type TransactorConnectionProvider interface {
 	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
 }
 
+type transactorOptions struct {
+	tx          sql.TxOptions
+	propagation storage.Propagation
+}
+
+// TransactorOption sets default options of transactions started by transactor
+type TransactorOption func(options *transactorOptions)
+
+// IsolationOption sets default isolation level of transactions
+func IsolationOption(level sql.IsolationLevel) TransactorOption {
+	return func(options *transactorOptions) {
+		options.tx.Isolation = level
+	}
+}
+
+// ReadOnlyOption sets default read only mode of transactions
+func ReadOnlyOption(readOnly bool) TransactorOption {
+	return func(options *transactorOptions) {
+		options.tx.ReadOnly = readOnly
+	}
+}
+
+// PropagationOption sets default propagation of transactions
+func PropagationOption(propagation storage.Propagation) TransactorOption {
+	return func(options *transactorOptions) {
+		options.propagation = propagation
+	}
+}
+
 type sqlTransactor struct {
 	provider TransactorConnectionProvider
+	options  transactorOptions
 }
 
-// NewTransactor creates SQL transactor
-func NewTransactor(provider TransactorConnectionProvider) storage.Transactor {
+// NewTransactor creates SQL transactor.
+//
+// By default, transactions use "read committed" isolation level and not read only mode.
+// If context already contain transaction, nested transaction (SAVEPOINT) is created.
+// Defaults can be overridden by "options" and for the single call by SetTxOptions & storage.SetPropagation
+func NewTransactor(provider TransactorConnectionProvider, options ...TransactorOption) storage.Transactor {
+	transactorOpts := transactorOptions{
+		tx: sql.TxOptions{
+			Isolation: sql.LevelReadCommitted,
+			ReadOnly:  false,
+		},
+		propagation: storage.PropagationNested,
+	}
+
+	for _, option := range options {
+		option(&transactorOpts)
+	}
+
 	return &sqlTransactor{
 		provider: provider,
+		options:  transactorOpts,
 	}
 }
 
@@ -28,54 +75,52 @@ func (st *sqlTransactor) Key() string {
 	return transactionKey
 }
 
+// Begin starts new transaction by transactor propagation
 func (st *sqlTransactor) Begin(ctx context.Context) (storage.Transaction, error) {
-	tx, err := st.provider.BeginTxx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
-		ReadOnly:  false,
-	})
+	txCtx, err := st.BeginCtx(ctx)
 	if err != nil {
-		return nil, ErrTransactorBegin.SetError(err)
+		return nil, err
 	}
 
-	return newTransactorTx(ctx, tx), nil
+	return newTransactorTx(txCtx), nil
 }
 
+// BeginCtx starts new transaction by transactor propagation and sets it to returned context.
+//
+// CommitCtx & RollbackCtx must be called with returned context
 func (st *sqlTransactor) BeginCtx(ctx context.Context) (context.Context, error) {
-	tx, err := st.provider.BeginTxx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
-		ReadOnly:  false,
-	})
+	txCtx, err := beginScope(ctx, st.provider, st.txOptions(ctx), st.options.propagation)
 	if err != nil {
-		return nil, ErrTransactorBegin.SetError(err)
+		return nil, err
 	}
 
-	return SetTx(ctx, tx), nil
+	return txCtx, nil
 }
 
 func (st *sqlTransactor) CommitCtx(ctx context.Context) error {
-	tx, ok := GetTx(ctx)
-	if !ok {
-		return nil
-	}
-
-	if err := tx.Commit(); err != nil {
-		return ErrTransactorCommit.SetError(err)
-	}
-
-	return nil
+	return commitScope(ctx)
 }
 
 func (st *sqlTransactor) RollbackCtx(ctx context.Context) error {
-	tx, ok := GetTx(ctx)
-	if !ok {
-		return nil
-	}
+	return rollbackScope(ctx)
+}
 
-	if err := tx.Rollback(); err != nil {
-		return ErrTransactorRollback.SetError(err)
+// txOptions returns default transactor options merged with options from context.
+//
+// Isolation level of context is used if it is set. Read only of context can only turn read only mode on
+func (st *sqlTransactor) txOptions(ctx context.Context) *sql.TxOptions {
+	opts := st.options.tx
+	if ctxOpts, ok := GetTxOptions(ctx); ok {
+		if ctxOpts.Isolation != sql.LevelDefault {
+			opts.Isolation = ctxOpts.Isolation
+		}
+
+		if ctxOpts.ReadOnly {
+			opts.ReadOnly = true
+		}
 	}
 
-	return nil
+	return &opts
 }
 
 func (st *sqlTransactor) IsTx(ctx context.Context) bool {
@@ -88,7 +133,7 @@ func (st *sqlTransactor) IsTx(ctx context.Context) bool {
 }
 
 func (st *sqlTransactor) TryCommit(ctx context.Context, err *error) {
-	if err != nil {
+	if err != nil && *err != nil {
 		_ = st.RollbackCtx(ctx)
 		return
 	}
@@ -97,25 +142,23 @@ func (st *sqlTransactor) TryCommit(ctx context.Context, err *error) {
 }
 
 type sqlTransaction struct {
-	tx        *sqlx.Tx
-	parentCtx context.Context
+	ctx context.Context
 }
 
-func newTransactorTx(ctx context.Context, tx *sqlx.Tx) storage.Transaction {
+func newTransactorTx(ctx context.Context) storage.Transaction {
 	return &sqlTransaction{
-		tx:        tx,
-		parentCtx: ctx,
+		ctx: ctx,
 	}
 }
 
 func (tx *sqlTransaction) Commit(_ context.Context) error {
-	return tx.tx.Commit()
+	return commitScope(tx.ctx)
 }
 
 func (tx *sqlTransaction) Rollback(_ context.Context) error {
-	return tx.tx.Rollback()
+	return rollbackScope(tx.ctx)
 }
 
 func (tx *sqlTransaction) Context() context.Context {
-	return SetTx(tx.parentCtx, tx.tx)
+	return tx.ctx
 }
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Attribute keys of query spans
+const (
+	AttributeDBSystem    = "db.system"
+	AttributeDBOperation = "db.operation"
+	AttributeDBStatement = "db.statement"
+	AttributeShardKey    = "db.shard_key"
+	AttributeTransaction = "db.transaction"
+)
+
+// Attribute is key-value pair attached to span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span is traced operation. Span must be ended by End
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. Returned context must contain started span,
+// so spans started with it become children
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+// QuerySpan describes query traced by StartQuerySpan
+type QuerySpan struct {
+	// System is database system, for example "postgres" or "redis"
+	System    string
+	Operation string
+	Statement string
+	// ShardKey is key of shard connection. Empty for single client
+	ShardKey    string
+	Transaction bool
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Pointer[tracerHolder]
+
+// SetTracer sets tracer used by all clients.
+//
+// By default, spans are not created
+func SetTracer(tracer Tracer) {
+	if tracer == nil {
+		globalTracer.Store(nil)
+		return
+	}
+
+	globalTracer.Store(&tracerHolder{
+		tracer: tracer,
+	})
+}
+
+// GetTracer returns tracer set by SetTracer or tracer which does nothing
+func GetTracer() Tracer {
+	holder := globalTracer.Load()
+	if holder == nil {
+		return noopTracer{}
+	}
+
+	return holder.tracer
+}
+
+// StartQuerySpan starts span of single query by global tracer
+func StartQuerySpan(ctx context.Context, query QuerySpan) (context.Context, Span) {
+	holder := globalTracer.Load()
+	if holder == nil {
+		return ctx, noopSpan{}
+	}
+
+	attributes := []Attribute{
+		{Key: AttributeDBSystem, Value: query.System},
+		{Key: AttributeDBOperation, Value: query.Operation},
+		{Key: AttributeTransaction, Value: query.Transaction},
+	}
+
+	if query.Statement != "" {
+		attributes = append(attributes, Attribute{Key: AttributeDBStatement, Value: query.Statement})
+	}
+
+	if query.ShardKey != "" {
+		attributes = append(attributes, Attribute{Key: AttributeShardKey, Value: query.ShardKey})
+	}
+
+	return holder.tracer.Start(ctx, query.System+"."+query.Operation, attributes...)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(_ ...Attribute) {}
+
+func (noopSpan) RecordError(_ error) {}
+
+func (noopSpan) End() {}
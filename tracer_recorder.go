@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is span saved by Recorder
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]any
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+	Ended      bool
+}
+
+// Recorder is Tracer which keeps spans in memory. Helpful in tests
+type Recorder struct {
+	mx    sync.Mutex
+	spans []*recordedSpan
+}
+
+// NewRecorder creates in-memory tracer
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	span := &recordedSpan{
+		span: RecordedSpan{
+			Name:       name,
+			Attributes: make(map[string]any, len(attributes)),
+			Start:      time.Now(),
+		},
+	}
+	span.SetAttributes(attributes...)
+
+	r.mx.Lock()
+	r.spans = append(r.spans, span)
+	r.mx.Unlock()
+
+	return ctx, span
+}
+
+// Spans returns copy of all started spans in start order
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(r.spans))
+	for _, span := range r.spans {
+		spans = append(spans, span.copy())
+	}
+
+	return spans
+}
+
+// Reset removes all recorded spans
+func (r *Recorder) Reset() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.spans = nil
+}
+
+type recordedSpan struct {
+	mx   sync.Mutex
+	span RecordedSpan
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...Attribute) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, attribute := range attributes {
+		s.span.Attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recordedSpan) End() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.span.Ended {
+		return
+	}
+
+	s.span.End = time.Now()
+	s.span.Ended = true
+}
+
+func (s *recordedSpan) copy() RecordedSpan {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	span := s.span
+	span.Attributes = maps.Clone(s.span.Attributes)
+	span.Errors = append([]error(nil), s.span.Errors...)
+	return span
+}
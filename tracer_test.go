@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStartQuerySpan(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     QuerySpan
+		wantName  string
+		wantAttrs map[string]any
+	}{
+		{
+			name:     "single client",
+			query:    QuerySpan{System: "postgres", Operation: "ExecContext", Statement: "UPDATE users SET name = $1"},
+			wantName: "postgres.ExecContext",
+			wantAttrs: map[string]any{
+				AttributeDBSystem:    "postgres",
+				AttributeDBOperation: "ExecContext",
+				AttributeDBStatement: "UPDATE users SET name = $1",
+				AttributeTransaction: false,
+			},
+		},
+		{
+			name:     "shard in transaction",
+			query:    QuerySpan{System: "redis", Operation: "get", ShardKey: "shard-1", Transaction: true},
+			wantName: "redis.get",
+			wantAttrs: map[string]any{
+				AttributeDBSystem:    "redis",
+				AttributeDBOperation: "get",
+				AttributeShardKey:    "shard-1",
+				AttributeTransaction: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			SetTracer(recorder)
+			t.Cleanup(func() {
+				SetTracer(nil)
+			})
+
+			_, span := StartQuerySpan(context.Background(), tt.query)
+			span.End()
+
+			spans := recorder.Spans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+
+			if spans[0].Name != tt.wantName {
+				t.Fatalf("expected span %q, got %q", tt.wantName, spans[0].Name)
+			}
+
+			if len(spans[0].Attributes) != len(tt.wantAttrs) {
+				t.Fatalf("expected attributes %v, got %v", tt.wantAttrs, spans[0].Attributes)
+			}
+
+			for key, value := range tt.wantAttrs {
+				if spans[0].Attributes[key] != value {
+					t.Fatalf("expected %s=%v, got %v", key, value, spans[0].Attributes[key])
+				}
+			}
+
+			if !spans[0].Ended {
+				t.Fatal("expected span to be ended")
+			}
+		})
+	}
+}
+
+func TestStartQuerySpanWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx := context.Background()
+	spanCtx, span := StartQuerySpan(ctx, QuerySpan{System: "postgres", Operation: "ExecContext"})
+	if spanCtx != ctx {
+		t.Fatal("expected context to be unchanged")
+	}
+
+	if _, ok := span.(noopSpan); !ok {
+		t.Fatalf("expected noop span, got %T", span)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	errQuery := errors.New("query failed")
+
+	recorder := NewRecorder()
+	_, span := recorder.Start(context.Background(), "first", Attribute{Key: "a", Value: 1})
+	span.SetAttributes(Attribute{Key: "b", Value: "x"})
+	span.RecordError(nil)
+	span.RecordError(errQuery)
+	span.End()
+
+	_, second := recorder.Start(context.Background(), "second")
+
+	spans := recorder.Spans()
+	if len(spans) != 2 || spans[0].Name != "first" || spans[1].Name != "second" {
+		t.Fatalf("expected spans in start order, got %v", spans)
+	}
+
+	first := spans[0]
+	if first.Attributes["a"] != 1 || first.Attributes["b"] != "x" {
+		t.Fatalf("unexpected attributes %v", first.Attributes)
+	}
+
+	if len(first.Errors) != 1 || first.Errors[0] != errQuery {
+		t.Fatalf("expected recorded error, got %v", first.Errors)
+	}
+
+	if !first.Ended || first.End.Before(first.Start) {
+		t.Fatalf("expected ended span, got %+v", first)
+	}
+
+	if spans[1].Ended {
+		t.Fatal("expected second span not to be ended")
+	}
+
+	// returned spans are copies
+	spans[0].Attributes["a"] = 2
+	if recorder.Spans()[0].Attributes["a"] != 1 {
+		t.Fatal("expected recorder to keep its own attributes")
+	}
+
+	second.End()
+	recorder.Reset()
+	if len(recorder.Spans()) != 0 {
+		t.Fatal("expected no spans after reset")
+	}
+}
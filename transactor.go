@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"golang.org/x/sync/errgroup"
@@ -20,6 +21,20 @@ type Transactor interface {
 	TryCommit(ctx context.Context, err *error)
 }
 
+// Preparer is optional extension of Transactor which supports two-phase commit.
+//
+// Used by transactor created by NewTwoPhaseTransactor.
+// If transaction from context is joined or nested, PrepareCtx does nothing
+// and CommitPreparedCtx/RollbackPreparedCtx work as CommitCtx/RollbackCtx
+type Preparer interface {
+	// PrepareCtx runs the first phase of commit: transaction is saved, but not committed yet
+	PrepareCtx(ctx context.Context) error
+	// CommitPreparedCtx commits transaction prepared by PrepareCtx
+	CommitPreparedCtx(ctx context.Context) error
+	// RollbackPreparedCtx rolls back transaction prepared by PrepareCtx
+	RollbackPreparedCtx(ctx context.Context) error
+}
+
 // Transaction interface using by Transactor
 type Transaction interface {
 	Context() context.Context
@@ -39,14 +54,37 @@ func RewriteTx(key string, original context.Context, toCopy context.Context) con
 
 type transactor struct {
 	transactors []Transactor
+	twoPhase    bool
+	decisions   DecisionStore
 }
 
+// NewTransactor creates transactor which manages transactions of all provided transactors at once.
+//
+// Transactions are committed in parallel, so if one of them fails others may be already committed
 func NewTransactor(transactors ...Transactor) Transactor {
 	return &transactor{
 		transactors: transactors,
 	}
 }
 
+// NewTwoPhaseTransactor creates transactor which commits transactions of provided transactors by two-phase commit.
+//
+// Transactors implementing Preparer are prepared first with identifiers of one global transaction (see BeginGlobalTx).
+// If any of them fails, all transactions are rolled back.
+// Then commit decision is saved to provided store, transactors which do not implement Preparer are committed one by one,
+// and only after that prepared transactions are committed.
+// So commit is atomic if at most one transactor does not implement Preparer.
+//
+// Transactions left prepared after crash are resolved by saved decision (see sql.RecoverPrepared).
+// Decision is removed after all prepared transactions are committed
+func NewTwoPhaseTransactor(decisions DecisionStore, transactors ...Transactor) Transactor {
+	return &transactor{
+		transactors: transactors,
+		twoPhase:    true,
+		decisions:   decisions,
+	}
+}
+
 func (t *transactor) Key() string {
 	builder := strings.Builder{}
 	for idx, tx := range t.transactors {
@@ -69,9 +107,11 @@ func (t *transactor) IsTx(ctx context.Context) bool {
 }
 
 func (t *transactor) Begin(ctx context.Context) (Transaction, error) {
+	propagation := GetPropagation(ctx)
+
 	transactions := make([]Transaction, 0, len(t.transactors))
 	for _, tr := range t.transactors {
-		tx, err := tr.Begin(ctx)
+		tx, err := tr.Begin(SetPropagation(ctx, propagation))
 		if err != nil {
 			return nil, err
 		}
@@ -82,28 +122,157 @@ func (t *transactor) Begin(ctx context.Context) (Transaction, error) {
 	return newTransaction(transactions), nil
 }
 
+// BeginCtx begins transactions of all transactors.
+//
+// Propagation set by SetPropagation is passed to every transactor
 func (t *transactor) BeginCtx(ctx context.Context) (context.Context, error) {
+	propagation := GetPropagation(ctx)
+
+	// hooks are created only for new transaction. Joined & nested transactions use hooks of outer transaction
+	var hooks *Hooks
+	if _, ok := GetHooks(ctx); !ok || propagation == PropagationRequiresNew {
+		ctx, hooks = lockHooks(ctx)
+	}
+
 	var err error
 	for _, tr := range t.transactors {
-		ctx, err = tr.BeginCtx(ctx)
+		ctx, err = tr.BeginCtx(SetPropagation(ctx, propagation))
 		if err != nil {
 			return ctx, err
 		}
 	}
 
-	return ctx, nil
+	return ResetPropagation(unlockHooks(ctx, hooks)), nil
 }
 
+// CommitCtx commits transactions of all transactors.
+//
+// If transactor began the outermost transaction, hooks run after commit:
+// commit hooks if all transactors committed and rollback hooks otherwise.
+// If two-phase commit fails at the second phase (ErrTwoPhaseCommit), some transactions may be already committed
+// and the rest stay prepared, so outcome is unknown and no hooks run
 func (t *transactor) CommitCtx(ctx context.Context) error {
+	if t.twoPhase {
+		if err := t.commitTwoPhase(ctx); err != nil {
+			if !errors.Is(err, ErrTwoPhaseCommit) {
+				ownedHooks(ctx).RunRollback()
+			}
+
+			return err
+		}
+
+		ownedHooks(ctx).RunCommit()
+		return nil
+	}
+
 	wg := errgroup.Group{}
 	for _, tx := range t.transactors {
 		wg.Go(func() error {
 			return tx.CommitCtx(ctx)
 		})
 	}
-	return wg.Wait()
+
+	if err := wg.Wait(); err != nil {
+		ownedHooks(ctx).RunRollback()
+		return err
+	}
+
+	ownedHooks(ctx).RunCommit()
+	return nil
+}
+
+// commitTwoPhase prepares all transactors implementing Preparer, saves commit decision,
+// commits others and then commits prepared ones
+func (t *transactor) commitTwoPhase(ctx context.Context) error {
+	if t.decisions == nil {
+		t.rollbackEach(ctx)
+		return ErrTwoPhasePrepare.SetError(ErrDecisionStoreMissing)
+	}
+
+	ctx, globalID, err := BeginGlobalTx(ctx)
+	if err != nil {
+		t.rollbackEach(ctx)
+		return ErrTwoPhasePrepare.SetError(err)
+	}
+
+	prepared := make([]Preparer, 0, len(t.transactors))
+	others := make([]Transactor, 0, len(t.transactors))
+
+	// rollbackAll rolls back prepared transactions & transactions of others starting from provided index
+	rollbackAll := func(from int) {
+		for _, preparer := range prepared {
+			_ = preparer.RollbackPreparedCtx(ctx)
+		}
+
+		for _, tx := range others[from:] {
+			_ = tx.RollbackCtx(ctx)
+		}
+	}
+
+	// phase 1: prepare
+	for idx, tx := range t.transactors {
+		preparer, ok := tx.(Preparer)
+		if !ok {
+			others = append(others, tx)
+			continue
+		}
+
+		if err := preparer.PrepareCtx(ctx); err != nil {
+			_ = tx.RollbackCtx(ctx)
+			for _, left := range t.transactors[idx+1:] {
+				_ = left.RollbackCtx(ctx)
+			}
+			rollbackAll(0)
+			return ErrTwoPhasePrepare.SetError(err)
+		}
+
+		prepared = append(prepared, preparer)
+	}
+
+	// decision is saved before the first commit, so transactions left prepared after crash are committed by recovery.
+	// Decision may be saved even if error is returned, so it is removed before rollback
+	if err = t.decisions.SaveCommit(ctx, globalID); err != nil {
+		_ = t.decisions.Forget(ctx, globalID)
+		rollbackAll(0)
+		return ErrTwoPhaseDecision.SetError(err)
+	}
+
+	// commit transactors without prepare support one by one while prepared transactions can be rolled back
+	for idx, tx := range others {
+		if err = tx.CommitCtx(ctx); err != nil {
+			_ = t.decisions.Forget(ctx, globalID)
+			rollbackAll(idx + 1)
+			return err
+		}
+	}
+
+	// phase 2: commit prepared
+	wg := errgroup.Group{}
+	for _, preparer := range prepared {
+		wg.Go(func() error {
+			return preparer.CommitPreparedCtx(ctx)
+		})
+	}
+
+	if err = wg.Wait(); err != nil {
+		// decision is kept, so transactions which stay prepared are committed by recovery
+		return ErrTwoPhaseCommit.SetError(err)
+	}
+
+	_ = t.decisions.Forget(ctx, globalID)
+	return nil
 }
 
+// rollbackEach rolls back transactions of all transactors without running hooks
+func (t *transactor) rollbackEach(ctx context.Context) {
+	for _, tx := range t.transactors {
+		_ = tx.RollbackCtx(ctx)
+	}
+}
+
+// RollbackCtx rolls back transactions of all transactors.
+//
+// If transactor began the outermost transaction, rollback hooks run after rollback
 func (t *transactor) RollbackCtx(ctx context.Context) error {
 	wg := errgroup.Group{}
 	for _, tx := range t.transactors {
@@ -111,11 +280,14 @@ func (t *transactor) RollbackCtx(ctx context.Context) error {
 			return tx.RollbackCtx(ctx)
 		})
 	}
-	return wg.Wait()
+
+	err := wg.Wait()
+	ownedHooks(ctx).RunRollback()
+	return err
 }
 
 func (t *transactor) TryCommit(ctx context.Context, err *error) {
-	if err != nil {
+	if err != nil && *err != nil {
 		_ = t.RollbackCtx(ctx)
 		return
 	}
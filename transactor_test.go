@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeTransactor records calls to shared log and fails on configured step
+type fakeTransactor struct {
+	name string
+	fail string
+	log  *[]string
+}
+
+func (f *fakeTransactor) call(name string) error {
+	if f.log != nil {
+		*f.log = append(*f.log, f.name+" "+name)
+	}
+
+	if f.fail == name {
+		return errors.New(name + " failed")
+	}
+
+	return nil
+}
+
+func (f *fakeTransactor) Key() string                       { return "fake" }
+func (f *fakeTransactor) IsTx(ctx context.Context) bool     { return false }
+func (f *fakeTransactor) TryCommit(context.Context, *error) {}
+
+func (f *fakeTransactor) Begin(ctx context.Context) (Transaction, error) {
+	return nil, nil
+}
+
+func (f *fakeTransactor) BeginCtx(ctx context.Context) (context.Context, error) {
+	return ctx, f.call("begin")
+}
+
+func (f *fakeTransactor) CommitCtx(ctx context.Context) error {
+	return f.call("commit")
+}
+
+func (f *fakeTransactor) RollbackCtx(ctx context.Context) error {
+	return f.call("rollback")
+}
+
+// fakePreparer is fake transactor with two-phase commit support
+type fakePreparer struct {
+	fakeTransactor
+}
+
+func (f *fakePreparer) PrepareCtx(ctx context.Context) error {
+	return f.call("prepare")
+}
+
+func (f *fakePreparer) CommitPreparedCtx(ctx context.Context) error {
+	return f.call("commit_prepared")
+}
+
+func (f *fakePreparer) RollbackPreparedCtx(ctx context.Context) error {
+	return f.call("rollback_prepared")
+}
+
+// fakeDecisions is fake decision store which records calls to shared log
+type fakeDecisions struct {
+	fakeTransactor
+	saved map[string]bool
+}
+
+func newFakeDecisions(log *[]string, fail string) *fakeDecisions {
+	return &fakeDecisions{
+		fakeTransactor: fakeTransactor{name: "decision", fail: fail, log: log},
+		saved:          make(map[string]bool),
+	}
+}
+
+func (f *fakeDecisions) SaveCommit(ctx context.Context, globalID string) error {
+	f.saved[globalID] = true
+	return f.call("save")
+}
+
+func (f *fakeDecisions) IsCommitted(ctx context.Context, globalID string) (bool, error) {
+	return f.saved[globalID], f.call("check")
+}
+
+func (f *fakeDecisions) Forget(ctx context.Context, globalID string) error {
+	delete(f.saved, globalID)
+	return f.call("forget")
+}
+
+func TestTwoPhaseTransactorCommit(t *testing.T) {
+	tests := []struct {
+		name         string
+		fail         string
+		failDecision string
+		noDecisions  bool
+		wantErr      error
+		wantHook     string
+		wantDecision bool
+	}{
+		{
+			name:     "committed",
+			wantHook: "commit",
+		},
+		{
+			name:     "prepare failed",
+			fail:     "prepare",
+			wantErr:  ErrTwoPhasePrepare,
+			wantHook: "rollback",
+		},
+		{
+			name:         "commit prepared failed",
+			fail:         "commit_prepared",
+			wantErr:      ErrTwoPhaseCommit,
+			wantDecision: true,
+		},
+		{
+			name:         "decision not saved",
+			failDecision: "save",
+			wantErr:      ErrTwoPhaseDecision,
+			wantHook:     "rollback",
+		},
+		{
+			name:        "decision store missing",
+			noDecisions: true,
+			wantErr:     ErrDecisionStoreMissing,
+			wantHook:    "rollback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &fakePreparer{}
+			second := &fakePreparer{fakeTransactor{fail: tt.fail}}
+			other := &fakeTransactor{}
+			decisions := newFakeDecisions(nil, tt.failDecision)
+
+			var store DecisionStore = decisions
+			if tt.noDecisions {
+				store = nil
+			}
+			transactor := NewTwoPhaseTransactor(store, first, second, other)
+
+			ctx, err := transactor.BeginCtx(context.Background())
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+
+			var hook string
+			_ = OnCommit(ctx, func(ctx context.Context) error {
+				hook = "commit"
+				return nil
+			})
+			_ = OnRollback(ctx, func(ctx context.Context) error {
+				hook = "rollback"
+				return nil
+			})
+
+			if err = transactor.CommitCtx(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if hook != tt.wantHook {
+				t.Fatalf("expected %q hooks to run, got %q", tt.wantHook, hook)
+			}
+
+			// decision is kept only if some prepared transactions may stay prepared
+			if saved := len(decisions.saved) > 0; saved != tt.wantDecision {
+				t.Fatalf("expected decision kept %v, got %v", tt.wantDecision, saved)
+			}
+		})
+	}
+}
+
+func TestTwoPhaseTransactorOrder(t *testing.T) {
+	var log []string
+	prepared := &fakePreparer{fakeTransactor{name: "prepared", log: &log}}
+	other := &fakeTransactor{name: "other", log: &log}
+	transactor := NewTwoPhaseTransactor(newFakeDecisions(&log, ""), prepared, other)
+
+	ctx, err := transactor.BeginCtx(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if err = transactor.CommitCtx(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	// decision is saved before the first commit.
+	// Transactor without prepare support is committed while prepared transaction can still be rolled back
+	want := []string{
+		"prepared begin",
+		"other begin",
+		"prepared prepare",
+		"decision save",
+		"other commit",
+		"prepared commit_prepared",
+		"decision forget",
+	}
+	if !slices.Equal(log, want) {
+		t.Fatalf("expected calls %v, got %v", want, log)
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// DecisionStore persists commit decisions of two-phase commit.
+//
+// Decision is saved after all transactions are prepared and before the first of them is committed,
+// so transactions left prepared after coordinator crash can be resolved by it:
+// transactions of global transaction with saved decision are committed, others are rolled back
+type DecisionStore interface {
+	// SaveCommit saves decision to commit global transaction
+	SaveCommit(ctx context.Context, globalID string) error
+	// IsCommitted checks if decision to commit global transaction was saved
+	IsCommitted(ctx context.Context, globalID string) (bool, error)
+	// Forget removes decision of global transaction which was committed by all participants
+	Forget(ctx context.Context, globalID string) error
+}
+
+type globalTxKey struct{}
+
+// globalTx is global transaction of two-phase commit. Every participant gets its own branch of it
+type globalTx struct {
+	id       string
+	branches atomic.Int64
+}
+
+// BeginGlobalTx creates global transaction of two-phase commit and sets it to context.
+//
+// Participants prepare their transactions with identifiers derived from global transaction (see GlobalTxBranch)
+func BeginGlobalTx(ctx context.Context) (context.Context, string, error) {
+	id, err := newSagaID()
+	if err != nil {
+		return ctx, "", err
+	}
+
+	return context.WithValue(ctx, globalTxKey{}, &globalTx{id: id}), id, nil
+}
+
+// GlobalTxBranch returns identifier of global transaction from context and the next number of its branch.
+//
+// Every call returns new branch, so every prepared transaction of global transaction has unique identifier
+func GlobalTxBranch(ctx context.Context) (globalID, branch string, ok bool) {
+	tx, ok := ctx.Value(globalTxKey{}).(*globalTx)
+	if !ok {
+		return "", "", false
+	}
+
+	return tx.id, strconv.FormatInt(tx.branches.Add(1), 10), true
+}
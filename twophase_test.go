@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGlobalTxBranch(t *testing.T) {
+	if _, _, ok := GlobalTxBranch(context.Background()); ok {
+		t.Fatal("expected no global transaction in empty context")
+	}
+
+	ctx, globalID, err := BeginGlobalTx(context.Background())
+	if err != nil {
+		t.Fatalf("begin global transaction: %v", err)
+	}
+
+	for _, want := range []string{"1", "2", "3"} {
+		id, branch, ok := GlobalTxBranch(ctx)
+		if !ok || id != globalID {
+			t.Fatalf("expected global transaction %q, got %q (%v)", globalID, id, ok)
+		}
+
+		if branch != want {
+			t.Fatalf("expected branch %q, got %q", want, branch)
+		}
+	}
+}